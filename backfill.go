@@ -0,0 +1,89 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/format"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/parser"
+)
+
+// backfill prints metrics recorded by sadc in a sysstat data file with their
+// original timestamps, so gaps in collected metrics can be filled
+func backfill(args []string, prefix parser.Prefix) int {
+	flags := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	file := flags.String("file", "", "sysstat data file to read, e.g. /var/log/sa/sa15 (required)")
+	start := flags.String("start", "", "time of day of the first record to read, hh:mm:ss")
+	end := flags.String("end", "", "time of day of the last record to read, hh:mm:ss")
+	output := flags.String("format", format.JSON, "output format, one of: "+strings.Join(format.Formats, ", "))
+	config := flags.String("config", "", "plugin configuration in JSON format, e.g. {\"Units\": \"bytes\"}")
+	var fs filters
+	flags.Var(&fs, "filter", "namespace of metrics to read, * matches any element (may be repeated)")
+	vendor, nsType := addPrefixFlags(flags, prefix)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	prefix, err := parser.NewPrefix(*vendor, *nsType)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "no data file given with --file")
+		return 2
+	}
+
+	cfg, err := parseConfig(*config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid config:", err)
+		return 2
+	}
+
+	collector := iostat.NewIostatCollector(prefix)
+	mts, err := collector.GetMetricTypes(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	mts = filterMetrics(mts, fs)
+	if len(mts) == 0 {
+		fmt.Fprintln(os.Stderr, "no metrics match the given filters")
+		return 1
+	}
+	for i := range mts {
+		mts[i].Config = cfg
+	}
+
+	recorded, err := collector.Backfill(mts, *file, *start, *end)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := format.Write(os.Stdout, *output, recorded); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
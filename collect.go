@@ -0,0 +1,130 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/format"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/parser"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+// filters holds namespaces given with repeated --filter flags
+type filters []string
+
+func (f *filters) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *filters) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+// collect runs a single collection and prints collected metrics
+func collect(args []string, prefix parser.Prefix) int {
+	flags := flag.NewFlagSet("collect", flag.ContinueOnError)
+	output := flags.String("format", format.JSON, "output format, one of: "+strings.Join(format.Formats, ", "))
+	config := flags.String("config", "", "plugin configuration in JSON format, e.g. {\"ReportSinceBoot\": true}")
+	var fs filters
+	flags.Var(&fs, "filter", "namespace of metrics to collect, * matches any element (may be repeated)")
+	vendor, nsType := addPrefixFlags(flags, prefix)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	prefix, err := parser.NewPrefix(*vendor, *nsType)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	cfg, err := parseConfig(*config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid config:", err)
+		return 2
+	}
+
+	collector := iostat.NewIostatCollector(prefix)
+	mts, err := collector.GetMetricTypes(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	mts = filterMetrics(mts, fs)
+	if len(mts) == 0 {
+		fmt.Fprintln(os.Stderr, "no metrics match the given filters")
+		return 1
+	}
+	for i := range mts {
+		mts[i].Config = cfg
+	}
+
+	collected, err := collector.CollectMetrics(mts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := format.Write(os.Stdout, *output, collected); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// filterMetrics returns metric types matching any of the filters; a concrete
+// value given in a filter replaces the dynamic element of a metric type
+func filterMetrics(mts []plugin.Metric, fs filters) []plugin.Metric {
+	if len(fs) == 0 {
+		return mts
+	}
+
+	filtered := []plugin.Metric{}
+	for _, f := range fs {
+		elems := strings.Split(strings.Trim(f, "/"), "/")
+		for _, mt := range mts {
+			if len(mt.Namespace) != len(elems) {
+				continue
+			}
+			ns := plugin.CopyNamespace(mt.Namespace)
+			matched := true
+			for i, e := range elems {
+				if e == "*" {
+					continue
+				}
+				if ns[i].IsDynamic() {
+					ns[i].Value = e
+				} else if ns[i].Value != e {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				mt.Namespace = ns
+				filtered = append(filtered, mt)
+			}
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,62 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iostat
+
+import (
+	"time"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/parser"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+const (
+	// anomalyMetric is a namespace element grouping anomaly z-scores of device metrics
+	anomalyMetric = "anomaly"
+
+	// baselines not updated for that many half-lives are forgotten
+	baselineExpiry = 10
+)
+
+// anomalyMetrics lists device metrics which are scored against their baseline
+var anomalyMetrics = []string{"await", "%util", "rkB_per_sec", "wkB_per_sec"}
+
+// addAnomalies adds z-scores of device metrics, named in the naming scheme,
+// against their rolling baselines; scores are added once a device has been
+// seen in enough collections
+func (iostat *Iostat) addAnomalies(cfg plugin.Config, data map[string]interface{}, naming string) {
+	halfLife := time.Duration(getFloat(cfg, cfgAnomalyHalfLife, defaultAnomalyHalfLife) * float64(time.Second))
+	warmup := getInt(cfg, cfgAnomalyWarmup, defaultAnomalyWarmup)
+	now := time.Now()
+
+	for _, dev := range devices(data) {
+		for _, legacy := range anomalyMetrics {
+			name := parser.Rename(legacy, naming)
+			v, ok := data[iostat.deviceNamespace(dev, name)].(float64)
+			if !ok {
+				continue
+			}
+			// baselines are shared by naming schemes
+			if z, ok := iostat.baseline.Score(iostat.deviceNamespace(dev, legacy), v, now, halfLife, warmup); ok {
+				data[iostat.deviceNamespace(dev, anomalyMetric, name)] = z
+			}
+		}
+	}
+	iostat.baseline.Expire(now.Add(-baselineExpiry * halfLife))
+}
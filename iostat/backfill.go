@@ -0,0 +1,96 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iostat
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/parser"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/sadf"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+// sadfTimeout is time allowed for sadf to export a data file
+const sadfTimeout = 60 * time.Second
+
+// Backfill returns requested CPU and device metrics recorded by sadc in the
+// sysstat data file, e.g. /var/log/sa/sa15, with timestamps of their records;
+// start and end limit the time of day (hh:mm:ss) of records if they are not empty.
+// Metrics are named and converted as collected ones, other metrics are not recorded.
+func (iostat *Iostat) Backfill(mts []plugin.Metric, file, start, end string) ([]plugin.Metric, error) {
+	cfg := configOf(mts)
+	naming, err := getNaming(cfg)
+	if err != nil {
+		return nil, err
+	}
+	unit, err := getUnit(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, mt := range mts {
+		if len(mt.Namespace) < 4 {
+			return nil, fmt.Errorf("Namespace length is too short (len = %d)", len(mt.Namespace))
+		}
+	}
+
+	reader, err := iostat.cmd.Run(sadf.Command, sadf.Args(file, start, end), sadfTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot export %s: %v", file, err)
+	}
+	records, err := sadf.Parse(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := []plugin.Metric{}
+	for _, record := range records {
+		data := map[string]interface{}{}
+		for name, v := range record.CPU {
+			data[iostat.joinNamespace(parser.Rename(cpuMetric, naming), parser.Rename(name, naming))] = v
+		}
+		for dev, values := range record.Disks {
+			for name, v := range values {
+				data[iostat.deviceNamespace(dev, parser.Rename(name, naming))] = v
+			}
+		}
+		_, data = convertUnits(nil, data, unit, naming)
+
+		idx := newIndex(data)
+		for _, mt := range mts {
+			for _, ns := range idx.match(mt.Namespace) {
+				metric := plugin.Metric{
+					Namespace: ns,
+					Data:      data[ns.String()],
+					Timestamp: record.Time,
+				}
+				if ns[2].Value == deviceMetric && isPattern(mt.Namespace[3].Value) {
+					metric.Tags = map[string]string{"dev": ns[3].Value}
+				}
+				metrics = append(metrics, metric)
+			}
+		}
+	}
+
+	for i := range metrics {
+		describe(&metrics[i], naming)
+	}
+	return metrics, nil
+}
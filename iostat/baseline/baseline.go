@@ -0,0 +1,108 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package baseline keeps exponentially weighted moving mean and variance of
+// series of values and scores new values against them
+package baseline
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// MaxScore bounds scores; values differing from a series which has not varied
+// at all, e.g. await of an idle device, have infinite z-score and get MaxScore
+const MaxScore = 100
+
+// Baseline holds moving statistics of series identified by keys
+type Baseline struct {
+	mutex  sync.Mutex
+	series map[string]*ewma
+}
+
+// ewma is exponentially weighted moving mean and variance of a series
+type ewma struct {
+	mean     float64
+	variance float64
+	count    int64
+	updated  time.Time
+}
+
+// New returns empty baseline
+func New() *Baseline {
+	return &Baseline{series: map[string]*ewma{}}
+}
+
+// Score returns z-score of the value against the baseline of the series and
+// then adds the value to the baseline; weight of past values halves every
+// halfLife, so series updated at different rates decay alike. The score is
+// bounded by MaxScore and not available (ok is false) until the series has
+// warmup values.
+func (b *Baseline) Score(key string, v float64, now time.Time, halfLife time.Duration, warmup int64) (z float64, ok bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	s, found := b.series[key]
+	if !found {
+		b.series[key] = &ewma{mean: v, count: 1, updated: now}
+		return 0, false
+	}
+
+	if s.count >= warmup {
+		z, ok = score(v, s.mean, s.variance), true
+	}
+
+	alpha := 1.0
+	if halfLife > 0 {
+		alpha = 1 - math.Exp(-math.Ln2*now.Sub(s.updated).Seconds()/halfLife.Seconds())
+	}
+	diff := v - s.mean
+	incr := alpha * diff
+	s.mean += incr
+	s.variance = (1 - alpha) * (s.variance + diff*incr)
+	s.count++
+	s.updated = now
+	return z, ok
+}
+
+// score returns z-score of the value bounded by MaxScore
+func score(v, mean, variance float64) float64 {
+	diff := v - mean
+	if diff == 0 {
+		return 0
+	}
+	z := diff / math.Sqrt(variance)
+	if math.IsInf(z, 0) || math.Abs(z) > MaxScore {
+		return math.Copysign(MaxScore, diff)
+	}
+	return z
+}
+
+// Expire removes series which were not updated since the given time, e.g.
+// of devices which were removed
+func (b *Baseline) Expire(before time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for key, s := range b.series {
+		if s.updated.Before(before) {
+			delete(b.series, key)
+		}
+	}
+}
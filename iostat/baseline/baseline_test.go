@@ -0,0 +1,81 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baseline
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBaseline(t *testing.T) {
+	start := time.Unix(1500000000, 0)
+
+	Convey("Given series of values score them against the baseline", t, func() {
+		b := New()
+		now := start
+		for i := 0; i < 20; i++ {
+			_, ok := b.Score("sda", float64(10+i%2), now, time.Minute, 10)
+			So(ok, ShouldEqual, i > 9)
+			now = now.Add(10 * time.Second)
+		}
+
+		z, ok := b.Score("sda", 10.5, now, time.Minute, 10)
+		So(ok, ShouldBeTrue)
+		So(z, ShouldAlmostEqual, 0, 0.5)
+
+		z, ok = b.Score("sda", 100, now.Add(10*time.Second), time.Minute, 10)
+		So(ok, ShouldBeTrue)
+		So(z, ShouldBeGreaterThan, 10)
+
+		Convey("keeping series separately", func() {
+			_, ok := b.Score("sdb", 100, now, time.Minute, 10)
+			So(ok, ShouldBeFalse)
+		})
+	})
+
+	Convey("Given constant series score values differing from it with the maximum score", t, func() {
+		b := New()
+		for i := 0; i < 3; i++ {
+			b.Score("sda", 0, start.Add(time.Duration(i)*time.Second), time.Minute, 2)
+		}
+		z, ok := b.Score("sda", 0, start.Add(3*time.Second), time.Minute, 2)
+		So(ok, ShouldBeTrue)
+		So(z, ShouldEqual, 0)
+		z, ok = b.Score("sda", 250, start.Add(4*time.Second), time.Minute, 2)
+		So(ok, ShouldBeTrue)
+		So(z, ShouldEqual, MaxScore)
+
+		b.Score("sdb", 5, start, time.Minute, 1)
+		z, ok = b.Score("sdb", 4, start.Add(time.Second), time.Minute, 1)
+		So(ok, ShouldBeTrue)
+		So(z, ShouldEqual, -MaxScore)
+	})
+
+	Convey("Given series not updated recently forget them", t, func() {
+		b := New()
+		b.Score("sda", 1, start, time.Minute, 1)
+		b.Score("sdb", 1, start.Add(time.Hour), time.Minute, 1)
+		b.Expire(start.Add(time.Minute))
+		So(b.series, ShouldHaveLength, 1)
+		So(b.series, ShouldContainKey, "sdb")
+	})
+}
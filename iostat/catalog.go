@@ -0,0 +1,313 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iostat
+
+import (
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/filesystem"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+// data types of metrics
+const (
+	typeFloat  = "float64"
+	typeUint   = "uint64"
+	typeInt    = "int64"
+	typeBool   = "bool"
+	typeString = "string"
+)
+
+// dataTypeTag tags metric types with the data type of their values
+const dataTypeTag = "data_type"
+
+// metricInfo describes a metric
+type metricInfo struct {
+	description string
+	unit        string
+	dataType    string
+}
+
+// catalog describes metrics by their namespaces without vendor, type and the
+// dynamic element, e.g. "device/await" describes /intel/iostat/device/[device_id]/await;
+// statistics over samples and anomaly scores are described by lookup
+var catalog = map[string]metricInfo{
+	"avg-cpu/%user":       {"The percentage of CPU utilization that occurred while executing at the user level (the application usage)", "%", typeFloat},
+	"avg-cpu/%nice":       {"The percentage of CPU utilization that occurred while executing at the user level with nice priority", "%", typeFloat},
+	"avg-cpu/%system":     {"The percentage of CPU utilization that occurred while executing at the system level (the kernel usage)", "%", typeFloat},
+	"avg-cpu/%iowait":     {"The percentage of time that the CPU or CPUs were idle during which the system had an outstanding disk I/O request", "%", typeFloat},
+	"avg-cpu/%steal":      {"The percentage of time spent in involuntary wait by the virtual CPU or CPUs while the hypervisor was servicing another virtual processor", "%", typeFloat},
+	"avg-cpu/%idle":       {"The percentage of time that the CPU or CPUs were idle and the systems did not have an outstanding disk I/O request", "%", typeFloat},
+	"device/rrqm_per_sec": {"The number of read requests merged per second queued to the device", "1/s", typeFloat},
+	"device/wrqm_per_sec": {"The number of write requests merged per second queued to the device", "1/s", typeFloat},
+	"device/drqm_per_sec": {"The number of discard requests merged per second queued to the device", "1/s", typeFloat},
+	"device/%rrqm":        {"The percentage of read requests merged together before being sent to the device", "%", typeFloat},
+	"device/%wrqm":        {"The percentage of write requests merged together before being sent to the device", "%", typeFloat},
+	"device/%drqm":        {"The percentage of discard requests merged together before being sent to the device", "%", typeFloat},
+	"device/r_per_sec":    {"The number of read requests issued to the device per second", "1/s", typeFloat},
+	"device/w_per_sec":    {"The number of write requests issued to the device per second", "1/s", typeFloat},
+	"device/d_per_sec":    {"The number of discard requests issued to the device per second", "1/s", typeFloat},
+	"device/f_per_sec":    {"The number of flush requests issued to the device per second", "1/s", typeFloat},
+	"device/rkB_per_sec":  {"The number of kilobytes read from the device per second", "kB/s", typeFloat},
+	"device/wkB_per_sec":  {"The number of kilobytes written to the device per second", "kB/s", typeFloat},
+	"device/dkB_per_sec":  {"The number of kilobytes discarded for the device per second", "kB/s", typeFloat},
+	"device/avgrq-sz":     {"The average size (in sectors) of the requests issued to the device", "sectors", typeFloat},
+	"device/areq-sz":      {"The average size (in kilobytes) of the requests issued to the device", "kB", typeFloat},
+	"device/rareq-sz":     {"The average size (in kilobytes) of the read requests issued to the device", "kB", typeFloat},
+	"device/wareq-sz":     {"The average size (in kilobytes) of the write requests issued to the device", "kB", typeFloat},
+	"device/dareq-sz":     {"The average size (in kilobytes) of the discard requests issued to the device", "kB", typeFloat},
+	"device/avgrq_bytes":  {"The average size (in bytes) of the requests issued to the device", "B", typeFloat},
+	"device/rareq_bytes":  {"The average size (in bytes) of the read requests issued to the device", "B", typeFloat},
+	"device/wareq_bytes":  {"The average size (in bytes) of the write requests issued to the device", "B", typeFloat},
+	"device/dareq_bytes":  {"The average size (in bytes) of the discard requests issued to the device", "B", typeFloat},
+	"device/avgqu-sz":     {"The average queue length of the requests issued to the device", "requests", typeFloat},
+	"device/aqu-sz":       {"The average queue length of the requests issued to the device", "requests", typeFloat},
+	"device/await":        {"The average time (in milliseconds) for I/O requests issued to the device to be served which includes the time spent by the requests in queue and the time spent servicing them", "ms", typeFloat},
+	"device/r_await":      {"The average time (in milliseconds) for read requests issued to the device to be served which includes the time spent by the requests in queue and the time spent servicing them", "ms", typeFloat},
+	"device/w_await":      {"The average time (in milliseconds) for write requests issued to the device to be served which includes the time spent by the requests in queue and the time spent servicing them", "ms", typeFloat},
+	"device/d_await":      {"The average time (in milliseconds) for discard requests issued to the device to be served which includes the time spent by the requests in queue and the time spent servicing them", "ms", typeFloat},
+	"device/f_await":      {"The average time (in milliseconds) for flush requests issued to the device to be served", "ms", typeFloat},
+	"device/svctm":        {"The average service time (in milliseconds) for I/O requests issued to the device - Warning! Do not trust this field; it will be removed in a future version of sysstat", "ms", typeFloat},
+	"device/%util":        {"Percentage of elapsed time during which I/O requests were issued to the device (bandwidth utilization for the device); device saturation occurs when this value is close to 100%", "%", typeFloat},
+
+	"device/queue/inflight_reads":      {"The number of read requests issued to the device driver and not yet completed", "requests", typeUint},
+	"device/queue/inflight_writes":     {"The number of write requests issued to the device driver and not yet completed", "requests", typeUint},
+	"device/queue/nr_requests":         {"The maximum number of requests which can be allocated in the block layer queue of the device", "requests", typeUint},
+	"device/queue/scheduler":           {"The active I/O scheduler of the device", "", typeString},
+	"device/queue/read_ahead_kb":       {"The maximum number of kilobytes to read-ahead for filesystems on the device", "kB", typeUint},
+	"device/queue/max_sectors_kb":      {"The maximum number of kilobytes the block layer allows for a filesystem request", "kB", typeUint},
+	"device/queue/rotational":          {"1 if the device is rotational (HDD), 0 otherwise (SSD, NVMe)", "", typeUint},
+	"device/queue/logical_block_size":  {"The logical block size of the device in bytes", "B", typeUint},
+	"device/queue/physical_block_size": {"The physical block size of the device in bytes", "B", typeUint},
+	"device/queue/size_bytes":          {"The size of the device in bytes", "B", typeUint},
+
+	"device/counter/reads_completed":  {"The total number of reads completed successfully since boot", "requests", typeUint},
+	"device/counter/reads_merged":     {"The total number of adjacent reads merged since boot", "requests", typeUint},
+	"device/counter/sectors_read":     {"The total number of sectors (512 bytes) read successfully since boot", "sectors", typeUint},
+	"device/counter/read_time_ms":     {"The total number of milliseconds spent by all reads since boot", "ms", typeUint},
+	"device/counter/writes_completed": {"The total number of writes completed successfully since boot", "requests", typeUint},
+	"device/counter/writes_merged":    {"The total number of adjacent writes merged since boot", "requests", typeUint},
+	"device/counter/sectors_written":  {"The total number of sectors (512 bytes) written successfully since boot", "sectors", typeUint},
+	"device/counter/write_time_ms":    {"The total number of milliseconds spent by all writes since boot", "ms", typeUint},
+	"device/counter/io_ticks_ms":      {"The total number of milliseconds spent doing I/Os since boot", "ms", typeUint},
+	"device/counter/time_in_queue_ms": {"The weighted number of milliseconds spent doing I/Os since boot", "ms", typeUint},
+
+	"device/zram/compression_ratio":     {"The ratio of uncompressed to compressed data size", "", typeFloat},
+	"device/zram/orig_data_size_bytes":  {"The size of uncompressed data stored in the zram device", "B", typeUint},
+	"device/zram/compr_data_size_bytes": {"The size of compressed data stored in the zram device", "B", typeUint},
+	"device/zram/mem_used_total_bytes":  {"The memory allocated for the zram device, including fragmentation and metadata", "B", typeUint},
+	"device/zram/mem_limit_bytes":       {"The maximum memory the zram device can use, 0 if it is not limited", "B", typeUint},
+	"device/zram/mem_used_max_bytes":    {"The maximum memory the zram device has used", "B", typeUint},
+	"device/zram/same_pages":            {"The number of pages filled with the same value, stored without memory allocation", "pages", typeUint},
+	"device/zram/pages_compacted":       {"The number of pages freed by compaction", "pages", typeUint},
+	"device/zram/huge_pages":            {"The number of incompressible pages", "pages", typeUint},
+	"device/zram/failed_reads":          {"The number of failed reads", "requests", typeUint},
+	"device/zram/failed_writes":         {"The number of failed writes", "requests", typeUint},
+	"device/zram/invalid_io":            {"The number of non-page-size-aligned I/O requests", "requests", typeUint},
+	"device/zram/notify_free":           {"The number of freed pages notified by the swap layer or discarded by the filesystem", "pages", typeUint},
+
+	"device/md/array_state":           {"The state of the md array (e.g. clean, active, degraded)", "", typeString},
+	"device/md/raid_disks":            {"The number of disks of the md array", "disks", typeUint},
+	"device/md/degraded":              {"The number of missing or failed disks of the md array", "disks", typeUint},
+	"device/md/sync_action":           {"The synchronization in progress (e.g. resync, recover, check), idle if there is none", "", typeString},
+	"device/md/sync_progress_percent": {"The progress of the synchronization in percent, 100 if there is none", "%", typeFloat},
+	"device/md/sync_speed_kB_per_sec": {"The speed of the synchronization in kilobytes per second", "kB/s", typeUint},
+
+	"device/health/saturation_score": {"The highest of %util, await and average queue size relative to their thresholds; the device is saturated when it reaches 1", "", typeFloat},
+	"device/health/saturated":        {"true if the saturation score reaches 1", "", typeBool},
+	"device/health/latency_class":    {"0 if await is below half of its threshold (normal), 1 if it is below the threshold (elevated), 2 otherwise (high)", "", typeInt},
+	"device/health/queue_growing":    {"true if average queue size grew in each of the last HealthQueueGrowth collections", "", typeBool},
+
+	"filesystem/bytes_total":     {"The size of the filesystem in bytes", "B", typeUint},
+	"filesystem/bytes_used":      {"The number of bytes used in the filesystem", "B", typeUint},
+	"filesystem/bytes_available": {"The number of bytes available to unprivileged users in the filesystem", "B", typeUint},
+	"filesystem/inodes_total":    {"The number of inodes of the filesystem", "inodes", typeUint},
+	"filesystem/inodes_used":     {"The number of inodes used in the filesystem", "inodes", typeUint},
+}
+
+// cpuMetrics are CPU metrics reported by iostat
+var cpuMetrics = []string{"%user", "%nice", "%system", "%iowait", "%steal", "%idle"}
+
+// deviceColumn is a device metric reported by iostat of sysstat versions
+// since the first one (inclusive) until the second one (exclusive, if set)
+type deviceColumn struct {
+	name  string
+	since []int64
+	until []int64
+}
+
+var (
+	sysstat10 = []int64{10, 2, 0}
+	sysstat12 = []int64{12, 0, 0}
+	// sysstat 12.1.2 reports discards and drops svctm, 12.1.6 reports flushes
+	sysstatDiscards = []int64{12, 1, 2}
+	sysstatFlushes  = []int64{12, 1, 6}
+)
+
+// deviceColumns are device metrics reported by supported versions of iostat
+var deviceColumns = []deviceColumn{
+	{"rrqm_per_sec", sysstat10, nil},
+	{"wrqm_per_sec", sysstat10, nil},
+	{"r_per_sec", sysstat10, nil},
+	{"w_per_sec", sysstat10, nil},
+	{"rkB_per_sec", sysstat10, nil},
+	{"wkB_per_sec", sysstat10, nil},
+	{"avgrq-sz", sysstat10, sysstat12},
+	{"avgqu-sz", sysstat10, sysstat12},
+	{"await", sysstat10, sysstat12},
+	{"r_await", sysstat10, nil},
+	{"w_await", sysstat10, nil},
+	{"svctm", sysstat10, sysstatDiscards},
+	{"%util", sysstat10, nil},
+	{"%rrqm", sysstat12, nil},
+	{"%wrqm", sysstat12, nil},
+	{"rareq-sz", sysstat12, nil},
+	{"wareq-sz", sysstat12, nil},
+	{"aqu-sz", sysstat12, nil},
+	{"d_per_sec", sysstatDiscards, nil},
+	{"dkB_per_sec", sysstatDiscards, nil},
+	{"drqm_per_sec", sysstatDiscards, nil},
+	{"%drqm", sysstatDiscards, nil},
+	{"d_await", sysstatDiscards, nil},
+	{"dareq-sz", sysstatDiscards, nil},
+	{"f_per_sec", sysstatFlushes, nil},
+	{"f_await", sysstatFlushes, nil},
+}
+
+// deviceColumnsOf returns names of device metrics reported by iostat of the
+// version, or by any supported version if the version is unknown
+func deviceColumnsOf(version []int64) []string {
+	names := []string{}
+	for _, c := range deviceColumns {
+		if version == nil || (!older(version, c.since) && (c.until == nil || older(version, c.until))) {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+// older checks whether version a is older than version b
+func older(a, b []int64) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// throughput units of metrics named after the unit
+var throughputUnits = map[string]string{
+	unitBytes: "B/s",
+	unitKB:    "kB/s",
+	unitMB:    "MB/s",
+}
+
+func init() {
+	// describe throughput metrics in each unit, e.g. device/rbytes_per_sec or device/md/sync_speed_MB_per_sec
+	unitNames := map[string]string{unitBytes: "bytes", unitMB: "megabytes"}
+	keys := []string{}
+	for key := range catalog {
+		if elems := strings.Split(key, "/"); throughputMetrics[elems[len(elems)-1]] {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		info := catalog[key]
+		for unit, unitName := range unitNames {
+			dataType := info.dataType
+			// integer kilobytes are not integer megabytes, see scaleUint
+			if dataType == typeUint && unitScales[unit] < 1 {
+				dataType = typeFloat
+			}
+			catalog[strings.Replace(key, unitKB, unit, 1)] = metricInfo{
+				description: strings.Replace(info.description, "kilobytes", unitName, 1),
+				unit:        throughputUnits[unit],
+				dataType:    dataType,
+			}
+		}
+	}
+}
+
+// lookup returns description of the metric named in the naming scheme
+func lookup(ns plugin.Namespace, naming string) (metricInfo, bool) {
+	if len(ns) < 3 {
+		return metricInfo{}, false
+	}
+	// the catalog lists metrics by their legacy names
+	elems := []string{}
+	for i, e := range ns[2:] {
+		// skip device or mount
+		if i == 1 && (ns[2].Value == deviceMetric || ns[2].Value == filesystem.FilesystemMetric) {
+			continue
+		}
+		elems = append(elems, legacyName(e.Value, naming))
+	}
+
+	if info, ok := catalog[strings.Join(elems, "/")]; ok {
+		return info, true
+	}
+	if len(elems) != 3 || elems[0] != deviceMetric {
+		return metricInfo{}, false
+	}
+
+	// statistics over samples, e.g. device/await/p95
+	for _, stat := range sampleStats {
+		if elems[2] != stat {
+			continue
+		}
+		info, ok := catalog[deviceMetric+"/"+elems[1]]
+		if !ok {
+			return metricInfo{}, false
+		}
+		info.description = stat + " of " + elems[1] + " over the sample window"
+		info.dataType = typeFloat
+		return info, true
+	}
+
+	// anomaly scores, e.g. device/anomaly/await
+	if elems[1] == anomalyMetric {
+		if _, ok := catalog[deviceMetric+"/"+elems[2]]; ok {
+			return metricInfo{
+				description: "The z-score of " + elems[2] + " against its rolling baseline",
+				dataType:    typeFloat,
+			}, true
+		}
+	}
+	return metricInfo{}, false
+}
+
+// describe sets description and unit of the metric, named in the naming scheme, from the catalog
+func describe(mt *plugin.Metric, naming string) {
+	if info, ok := lookup(mt.Namespace, naming); ok {
+		mt.Description = info.description
+		mt.Unit = info.unit
+	}
+}
+
+// advertise describes the metric type like describe and tags it with the data
+// type of the metric; collected metrics are not tagged, they hold the data itself
+func advertise(mt *plugin.Metric, naming string) {
+	if info, ok := lookup(mt.Namespace, naming); ok {
+		mt.Description = info.description
+		mt.Unit = info.unit
+		mt.Tags = withTags(mt.Tags, map[string]string{dataTypeTag: info.dataType})
+	}
+}
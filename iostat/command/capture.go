@@ -0,0 +1,204 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	// ArgsExt is the extension of files holding command and arguments of recorded runs
+	ArgsExt = ".args"
+	// StatusExt is the extension of files holding exit status of recorded runs
+	StatusExt = ".status"
+	// VersionStatusExt is the extension of files holding exit status of exec of recorded runs
+	VersionStatusExt = ".version-status"
+)
+
+// recordExts are extensions of files of a recorded run
+var recordExts = map[string]bool{
+	ReportExt:        true,
+	ArgsExt:          true,
+	StatusExt:        true,
+	VersionExt:       true,
+	VersionStatusExt: true,
+}
+
+type runner interface {
+	Run(cmd string, args []string, timeout time.Duration) (io.Reader, error)
+	Exec(cmd string, args []string) string
+}
+
+// execsWithStatus is a runner which reports failures of exec
+type execsWithStatus interface {
+	ExecStatus(cmd string, args []string) (string, error)
+}
+
+// capture records runs of a runner into a directory, so they can be replayed:
+// output of each run, failed ones included, goes to a report file, its command
+// with arguments and exit status to files of the same name, and so do output
+// and exit status of the latest exec (iostat -V) preceding the run. Records of
+// the oldest runs are removed when the directory exceeds its size.
+type capture struct {
+	runner  runner
+	dir     string
+	maxSize int64
+
+	mutex sync.Mutex
+	seq   int
+	// version and versionStatus are output and status of the latest exec, nil if there was none
+	version       []byte
+	versionStatus []byte
+}
+
+// NewCapture returns runner recording runs of r into dir of at most maxSize bytes
+func NewCapture(r runner, dir string, maxSize int64) *capture {
+	return &capture{runner: r, dir: dir, maxSize: maxSize}
+}
+
+func (c *capture) Run(cmd string, args []string, timeout time.Duration) (io.Reader, error) {
+	reader, err := c.runner.Run(cmd, args, timeout)
+	var out []byte
+	if err == nil {
+		if out, err = ioutil.ReadAll(reader); err != nil {
+			return nil, err
+		}
+	} else if e, ok := err.(*RunError); ok {
+		out = e.Output
+	}
+
+	c.record(strings.Join(append([]string{cmd}, args...), " "), out, err)
+
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(out), nil
+}
+
+func (c *capture) Exec(cmd string, args []string) string {
+	var out string
+	var err error
+	if r, ok := c.runner.(execsWithStatus); ok {
+		if out, err = r.ExecStatus(cmd, args); err != nil {
+			log.Error(err)
+		}
+	} else {
+		out = c.runner.Exec(cmd, args)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.version = []byte(out)
+	c.versionStatus = status(err)
+	return out
+}
+
+// record writes files of a run named after its time, so they are sorted in
+// the order of runs
+func (c *capture) record(cmdline string, out []byte, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.seq++
+	name := fmt.Sprintf("%s-%06d", time.Now().UTC().Format("20060102T150405.000000000"), c.seq)
+	files := map[string][]byte{
+		name + ArgsExt:   []byte(cmdline + "\n"),
+		name + StatusExt: status(err),
+		name + ReportExt: out,
+	}
+	if c.version != nil {
+		files[name+VersionExt] = c.version
+		files[name+VersionStatusExt] = c.versionStatus
+	}
+	for file, content := range files {
+		if err := c.write(file, content); err != nil {
+			log.WithField("error", err).Warn("cannot capture run of " + cmdline)
+			return
+		}
+	}
+	if err := c.rotate(); err != nil {
+		log.WithField("error", err).Warn("cannot remove old captured runs")
+	}
+}
+
+// status returns content of a status file of a run which failed with err:
+// "0" if it succeeded, otherwise its exit status (-1 if it did not exit by
+// itself) followed by the error on the next line
+func status(err error) []byte {
+	if err == nil {
+		return []byte("0\n")
+	}
+	return []byte(fmt.Sprintf("%d\n%v\n", ExitStatus(err), err))
+}
+
+func (c *capture) write(file string, content []byte) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(c.dir, file), content, 0644)
+}
+
+// rotate removes files of the oldest runs until the directory fits its size;
+// files of the latest run are kept
+func (c *capture) rotate() error {
+	infos, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	var total int64
+	sizes := map[string]int64{}
+	runs := map[string][]string{}
+	for _, info := range infos {
+		total += info.Size()
+		ext := filepath.Ext(info.Name())
+		run := strings.TrimSuffix(info.Name(), ext)
+		if info.IsDir() || !recordExts[ext] {
+			continue
+		}
+		sizes[run] += info.Size()
+		runs[run] = append(runs[run], info.Name())
+	}
+
+	names := make([]string, 0, len(runs))
+	for run := range runs {
+		names = append(names, run)
+	}
+	sort.Strings(names)
+	for i := 0; i < len(names)-1 && total > c.maxSize; i++ {
+		for _, file := range runs[names[i]] {
+			if err := os.Remove(filepath.Join(c.dir, file)); err != nil {
+				return err
+			}
+		}
+		total -= sizes[names[i]]
+	}
+	return nil
+}
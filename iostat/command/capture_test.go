@@ -0,0 +1,186 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type mockRunner struct {
+	out     string
+	err     error
+	version string
+}
+
+func (m *mockRunner) Run(cmd string, args []string, timeout time.Duration) (io.Reader, error) {
+	if m.err != nil {
+		return nil, &RunError{Err: m.err, Output: []byte(m.out)}
+	}
+	return strings.NewReader(m.out), nil
+}
+
+func (m *mockRunner) Exec(cmd string, args []string) string {
+	if m.version != "" {
+		return "sysstat version " + m.version + "\n"
+	}
+	return "sysstat version 12.5.4\n"
+}
+
+func TestCapture(t *testing.T) {
+	Convey("Given capture directory record runs into it", t, func() {
+		dir, err := ioutil.TempDir("", "capture")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		runner := &mockRunner{out: "report 1\n"}
+		c := NewCapture(runner, filepath.Join(dir, "iostat"), 1<<20)
+		So(c.Exec("iostat", []string{"-V"}), ShouldEqual, "sysstat version 12.5.4\n")
+		reader, err := c.Run("iostat", []string{"-x", "-k"}, time.Second)
+		So(err, ShouldBeNil)
+		out, err := ioutil.ReadAll(reader)
+		So(err, ShouldBeNil)
+		So(string(out), ShouldEqual, "report 1\n")
+
+		runner.out = "iostat: invalid option -- 'x'\n"
+		runner.err = errors.New("exit status 1")
+		_, err = c.Run("iostat", []string{"-x"}, time.Second)
+		So(err, ShouldNotBeNil)
+
+		read := func(pattern string) []string {
+			files, err := filepath.Glob(filepath.Join(dir, "iostat", pattern))
+			So(err, ShouldBeNil)
+			contents := []string{}
+			for _, file := range files {
+				content, err := ioutil.ReadFile(file)
+				So(err, ShouldBeNil)
+				contents = append(contents, string(content))
+			}
+			return contents
+		}
+		So(read(VersionFile), ShouldBeEmpty)
+		So(read("*"+VersionExt), ShouldResemble, []string{"sysstat version 12.5.4\n", "sysstat version 12.5.4\n"})
+		So(read("*"+VersionStatusExt), ShouldResemble, []string{"0\n", "0\n"})
+		So(read("*"+ReportExt), ShouldResemble, []string{"report 1\n", "iostat: invalid option -- 'x'\n"})
+		So(read("*"+ArgsExt), ShouldResemble, []string{"iostat -x -k\n", "iostat -x\n"})
+		// the mock error is not an exit error of a process
+		So(read("2*"+StatusExt), ShouldResemble, []string{"0\n", "-1\nexit status 1\n"})
+
+		Convey("which can be replayed", func() {
+			r := NewReplay(filepath.Join(dir, "iostat"), false)
+			So(r.Exec("iostat", []string{"-V"}), ShouldEqual, "sysstat version 12.5.4\n")
+			reader, err := r.Run("iostat", nil, time.Second)
+			So(err, ShouldBeNil)
+			out, err := ioutil.ReadAll(reader)
+			So(err, ShouldBeNil)
+			So(string(out), ShouldEqual, "report 1\n")
+
+			_, err = r.Run("iostat", nil, time.Second)
+			So(err, ShouldNotBeNil)
+			So(err.(*RunError).Output, ShouldResemble, []byte("iostat: invalid option -- 'x'\n"))
+		})
+	})
+
+	Convey("Given failing command record its output and exit status", t, func() {
+		dir, err := ioutil.TempDir("", "capture")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c := NewCapture(New(), dir, 1<<20)
+		So(c.Exec("sh", []string{"-c", "echo old; exit 2"}), ShouldEqual, "old\n")
+		_, err = c.Run("sh", []string{"-c", "echo broken; exit 3"}, time.Second)
+		So(err, ShouldNotBeNil)
+		So(ExitStatus(err), ShouldEqual, 3)
+
+		read := func(pattern string) string {
+			files, err := filepath.Glob(filepath.Join(dir, pattern))
+			So(err, ShouldBeNil)
+			So(files, ShouldHaveLength, 1)
+			content, err := ioutil.ReadFile(files[0])
+			So(err, ShouldBeNil)
+			return string(content)
+		}
+		So(read("*"+ReportExt), ShouldEqual, "broken\n")
+		So(read("2*"+StatusExt), ShouldEqual, "3\nexit status 3\n")
+		So(read("*"+VersionExt), ShouldEqual, "old\n")
+		So(read("*"+VersionStatusExt), ShouldEqual, "2\nexit status 2\n")
+	})
+
+	Convey("Given version changing between runs replay the version of each run", t, func() {
+		dir, err := ioutil.TempDir("", "capture")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		runner := &mockRunner{out: "report\n", version: "11.6.1"}
+		c := NewCapture(runner, dir, 1<<20)
+		for _, version := range []string{"11.6.1", "12.5.4"} {
+			runner.version = version
+			c.Exec("iostat", []string{"-V"})
+			_, err := c.Run("iostat", nil, time.Second)
+			So(err, ShouldBeNil)
+		}
+
+		r := NewReplay(dir, true)
+		for _, version := range []string{"11.6.1", "12.5.4", "11.6.1"} {
+			So(r.Exec("iostat", []string{"-V"}), ShouldEqual, "sysstat version "+version+"\n")
+			_, err := r.Run("iostat", nil, time.Second)
+			So(err, ShouldBeNil)
+		}
+	})
+
+	Convey("Given size of capture directory remove the oldest runs", t, func() {
+		dir, err := ioutil.TempDir("", "capture")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c := NewCapture(&mockRunner{out: strings.Repeat("x", 100)}, dir, 300)
+		c.Exec("iostat", []string{"-V"})
+		for i := 0; i < 5; i++ {
+			_, err := c.Run("iostat", nil, time.Second)
+			So(err, ShouldBeNil)
+		}
+		reports, err := filepath.Glob(filepath.Join(dir, "*"+ReportExt))
+		So(err, ShouldBeNil)
+		So(reports, ShouldHaveLength, 2)
+		So(filepath.Base(reports[1]), ShouldEndWith, "000005"+ReportExt)
+		// versions are removed together with their runs
+		versions, err := filepath.Glob(filepath.Join(dir, "*"+VersionExt))
+		So(err, ShouldBeNil)
+		So(versions, ShouldHaveLength, 2)
+		So(filepath.Base(versions[1]), ShouldEndWith, "000005"+VersionExt)
+
+		Convey("keeping the latest run even if it does not fit", func() {
+			c := NewCapture(&mockRunner{out: strings.Repeat("x", 1000)}, dir, 300)
+			_, err := c.Run("iostat", nil, time.Second)
+			So(err, ShouldBeNil)
+			reports, err := filepath.Glob(filepath.Join(dir, "*"+ReportExt))
+			So(err, ShouldBeNil)
+			So(reports, ShouldHaveLength, 1)
+		})
+	})
+}
@@ -21,47 +21,103 @@ package command
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"strings"
+	"syscall"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 )
 
+// environment overrides variables of the plugin environment, so iostat output
+// does not depend on locale of the host
+var environment = map[string]string{
+	// decimal point and column names as in the C locale
+	"LC_ALL": "C",
+	// timestamps of reports in ISO 8601 format
+	"S_TIME_FORMAT": "ISO",
+}
+
+// RunError is an error of a command which failed, with the output it printed
+type RunError struct {
+	Err    error
+	Output []byte
+}
+
+func (e *RunError) Error() string {
+	return e.Err.Error()
+}
+
+// ExitStatus returns exit status of a command which failed with err, or -1
+// if the command did not exit by itself (e.g. it was not found or timed out)
+func ExitStatus(err error) int {
+	if e, ok := err.(*RunError); ok {
+		err = e.Err
+	}
+	if e, ok := err.(*exec.ExitError); ok {
+		if status, ok := e.Sys().(syscall.WaitStatus); ok {
+			return status.ExitStatus()
+		}
+	}
+	return -1
+}
+
 type cmdRunner struct{}
 
 func New() *cmdRunner {
 	return &cmdRunner{}
 }
 
-func (c *cmdRunner) Run(cmd string, args []string) (io.Reader, error) {
-	command := exec.Command(cmd, args...)
-	resCh := make(chan []byte)
-	errCh := make(chan error)
-	go func() {
-		out, err := command.CombinedOutput()
-		if err != nil {
-			errCh <- err
-		}
-		resCh <- out
-	}()
-	timer := time.After(2 * time.Second)
-	select {
-	case err := <-errCh:
-		return nil, err
-	case res := <-resCh:
-		return bytes.NewReader(res), nil
-	case <-timer:
-		return nil, fmt.Errorf("time out (cmd:%v args:%v)", cmd, args)
+// Run returns output of the command, which is killed if it does not end
+// before the timeout
+func (c *cmdRunner) Run(cmd string, args []string, timeout time.Duration) (io.Reader, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	command := exec.CommandContext(ctx, cmd, args...)
+	command.Env = env(os.Environ())
+	out, err := command.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, &RunError{Err: fmt.Errorf("time out (cmd:%v args:%v)", cmd, args), Output: out}
 	}
+	if err != nil {
+		return nil, &RunError{Err: err, Output: out}
+	}
+	return bytes.NewReader(out), nil
 }
 
 func (c *cmdRunner) Exec(cmd string, args []string) string {
+	out, err := c.ExecStatus(cmd, args)
+	if err != nil {
+		log.Error(err)
+	}
+	return out
+}
+
+// ExecStatus returns output of the command and its error if it failed
+func (c *cmdRunner) ExecStatus(cmd string, args []string) (string, error) {
 	command := exec.Command(cmd, args...)
+	command.Env = env(os.Environ())
 	outputBytes, err := command.CombinedOutput()
 	if err != nil {
-		log.Error(err)
+		return string(outputBytes), &RunError{Err: err, Output: outputBytes}
+	}
+	return string(outputBytes), nil
+}
+
+// env returns variables with those of environment overridden
+func env(vars []string) []string {
+	result := []string{}
+	for _, v := range vars {
+		if _, ok := environment[strings.SplitN(v, "=", 2)[0]]; !ok {
+			result = append(result, v)
+		}
+	}
+	for k, v := range environment {
+		result = append(result, k+"="+v)
 	}
-	return string(outputBytes[:])
+	return result
 }
@@ -0,0 +1,55 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCommand(t *testing.T) {
+	Convey("Given command which does not end in time kill it", t, func() {
+		dir, err := ioutil.TempDir("", "command")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		pidFile := filepath.Join(dir, "pid")
+
+		start := time.Now()
+		_, err = New().Run("sh", []string{"-c", "echo $$ > " + pidFile + "; exec sleep 5"}, 200*time.Millisecond)
+		So(err, ShouldNotBeNil)
+		So(ExitStatus(err), ShouldEqual, -1)
+		So(time.Since(start), ShouldBeLessThan, 2*time.Second)
+
+		content, err := ioutil.ReadFile(pidFile)
+		So(err, ShouldBeNil)
+		pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		So(err, ShouldBeNil)
+		// signal 0 checks whether the process exists
+		So(syscall.Kill(pid, 0), ShouldEqual, syscall.ESRCH)
+	})
+}
@@ -0,0 +1,147 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	// ReportExt is the extension of files holding recorded output of iostat runs
+	ReportExt = ".out"
+	// VersionExt is the extension of files holding recorded output of iostat -V preceding runs
+	VersionExt = ".version"
+	// VersionFile is the file holding recorded output of iostat -V used for
+	// reports recorded without it, e.g. collected by hand
+	VersionFile = "version"
+)
+
+// replay replays output of iostat recorded in a directory instead of running
+// it: each run returns the next report file in the order of their names, or
+// fails if the run was recorded with a nonzero exit status
+type replay struct {
+	dir  string
+	loop bool
+
+	mutex sync.Mutex
+	next  int
+}
+
+// NewReplay returns runner replaying output recorded in dir; after the last
+// report it starts again from the first one if loop is set, otherwise runs fail
+func NewReplay(dir string, loop bool) *replay {
+	return &replay{dir: dir, loop: loop}
+}
+
+func (r *replay) Run(cmd string, args []string, timeout time.Duration) (io.Reader, error) {
+	r.mutex.Lock()
+	report, err := r.report(cmd)
+	if err == nil {
+		r.next++
+	}
+	r.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := ioutil.ReadFile(report)
+	if err != nil {
+		return nil, err
+	}
+	if failure, failed := recordedFailure(strings.TrimSuffix(report, ReportExt) + StatusExt); failed {
+		return nil, &RunError{
+			Err:    fmt.Errorf("Recorded run of %s failed (%s)", cmd, failure),
+			Output: out,
+		}
+	}
+	return bytes.NewReader(out), nil
+}
+
+// Exec returns recorded output of iostat -V, the only command the plugin execs,
+// preceding the next run; the version file is used if it was not recorded
+func (r *replay) Exec(cmd string, args []string) string {
+	r.mutex.Lock()
+	report, err := r.report(cmd)
+	r.mutex.Unlock()
+
+	file := filepath.Join(r.dir, VersionFile)
+	if err == nil {
+		if run := strings.TrimSuffix(report, ReportExt); exists(run + VersionExt) {
+			file = run + VersionExt
+			if failure, failed := recordedFailure(run + VersionStatusExt); failed {
+				log.Errorf("Recorded exec of %s failed (%s)", cmd, failure)
+			}
+		}
+	}
+	out, err := ioutil.ReadFile(file)
+	if err != nil {
+		log.Error(err)
+	}
+	return string(out)
+}
+
+// report returns the report file replayed by the next run, starting again from
+// the first one after the last one if loop is set; r.mutex has to be held
+func (r *replay) report(cmd string) (string, error) {
+	reports, err := filepath.Glob(filepath.Join(r.dir, "*"+ReportExt))
+	if err != nil {
+		return "", err
+	}
+	if len(reports) == 0 {
+		return "", fmt.Errorf("No recorded output of %s found in %s", cmd, r.dir)
+	}
+	sort.Strings(reports)
+
+	if r.next >= len(reports) {
+		if !r.loop {
+			return "", fmt.Errorf("All recorded output of %s in %s was replayed", cmd, r.dir)
+		}
+		r.next = 0
+	}
+	return reports[r.next], nil
+}
+
+// recordedFailure returns exit status and error of a recorded run read from
+// its status file, if the run failed; runs without the status file succeeded
+func recordedFailure(file string) (string, bool) {
+	status, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", false
+	}
+	lines := strings.SplitN(strings.TrimSpace(string(status)), "\n", 2)
+	return strings.Join(lines, ": "), lines[0] != "0"
+}
+
+// exists checks whether the file exists
+func exists(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
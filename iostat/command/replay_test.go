@@ -0,0 +1,76 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReplay(t *testing.T) {
+	run := func(r *replay) (string, error) {
+		reader, err := r.Run("iostat", []string{"-x"}, time.Second)
+		if err != nil {
+			return "", err
+		}
+		out, err := ioutil.ReadAll(reader)
+		return string(out), err
+	}
+
+	Convey("Given recorded output replay it report by report", t, func() {
+		r := NewReplay("../testdata/replay", false)
+		So(r.Exec("iostat", []string{"-V"}), ShouldStartWith, "sysstat version 11.2.0")
+
+		out, err := run(r)
+		So(err, ShouldBeNil)
+		So(out, ShouldContainSubstring, "98.15")
+		out, err = run(r)
+		So(err, ShouldBeNil)
+		So(out, ShouldContainSubstring, "90.00")
+
+		Convey("failing at the end unless looping", func() {
+			_, err := run(r)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given looping replay start again after the last report", t, func() {
+		r := NewReplay("../testdata/replay", true)
+		outs := []string{}
+		for i := 0; i < 3; i++ {
+			out, err := run(r)
+			So(err, ShouldBeNil)
+			outs = append(outs, out)
+		}
+		So(outs[2], ShouldEqual, outs[0])
+		So(strings.Contains(outs[1], "90.00"), ShouldBeTrue)
+	})
+
+	Convey("Given directory without recorded output return an error", t, func() {
+		r := NewReplay("../testdata/host", true)
+		_, err := run(r)
+		So(err, ShouldNotBeNil)
+		So(r.Exec("iostat", []string{"-V"}), ShouldBeEmpty)
+	})
+}
@@ -0,0 +1,223 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iostat
+
+import (
+	"fmt"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/parser"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+const (
+	// cfgReportSinceBoot makes iostat report statistics since boot instead of sampling them
+	cfgReportSinceBoot = "ReportSinceBoot"
+	// cfgHostRoot is a directory where host root filesystem is mounted, detected when empty
+	cfgHostRoot = "HostRoot"
+	// cfgSamples is a number of samples taken per collection
+	cfgSamples = "Samples"
+	// cfgSampleWindow is a length of time in seconds over which samples are taken
+	cfgSampleWindow = "SampleWindow"
+	// cfgMode selects how statistics are collected
+	cfgMode = "Mode"
+	// cfgTask identifies the task for state kept between collections (snapshots in
+	// interval mode, queue trends), tasks requesting the same metrics at different
+	// intervals have to set different values to keep their own state
+	cfgTask = "Task"
+	// cfgHealthUtil is %util above which a device is considered saturated
+	cfgHealthUtil = "HealthUtil"
+	// cfgHealthAwaitHDD and cfgHealthAwaitSSD are await in ms above which latency
+	// of a rotational or non-rotational device is considered high
+	cfgHealthAwaitHDD = "HealthAwaitHDD"
+	cfgHealthAwaitSSD = "HealthAwaitSSD"
+	// cfgHealthQueueHDD and cfgHealthQueueSSD are average queue sizes above which
+	// a rotational or non-rotational device is considered saturated
+	cfgHealthQueueHDD = "HealthQueueHDD"
+	cfgHealthQueueSSD = "HealthQueueSSD"
+	// cfgHealthQueueGrowth is a number of consecutive collections in which average
+	// queue size of a device has to grow for its queue to be considered growing
+	cfgHealthQueueGrowth = "HealthQueueGrowth"
+	// cfgAnomalyHalfLife is a time in seconds after which weight of a value in the baseline halves
+	cfgAnomalyHalfLife = "AnomalyHalfLife"
+	// cfgAnomalyWarmup is a number of values in the baseline required before anomalies are scored
+	cfgAnomalyWarmup = "AnomalyWarmup"
+	// cfgTopDevices is a number of the busiest devices which metrics are collected, all if not positive
+	cfgTopDevices = "TopDevices"
+	// cfgTopDevicesBy is a metric devices are ranked by
+	cfgTopDevicesBy = "TopDevicesBy"
+	// cfgHideNvmePaths hides metrics of paths of NVMe namespaces through their controllers
+	cfgHideNvmePaths = "HideNvmePaths"
+	// cfgFilesystemTypes lists comma separated types of filesystems which metrics are
+	// collected even if they are not backed by a block device, e.g. nfs4 or tmpfs
+	cfgFilesystemTypes = "FilesystemTypes"
+	// cfgUnits is a unit of throughput: bytes, kB or MB
+	cfgUnits = "Units"
+	// cfgNaming is a naming scheme of metrics: legacy or snake_case
+	cfgNaming = "Naming"
+	// cfgReplay is a directory with recorded output of iostat which is replayed instead of running it
+	cfgReplay = "Replay"
+	// cfgReplayLoop makes replay start again from the first recorded report after the last one
+	cfgReplayLoop = "ReplayLoop"
+	// cfgCapture is a directory into which runs of iostat are recorded, so they can be replayed
+	cfgCapture = "Capture"
+	// cfgCaptureSize is the maximum size of the capture directory in bytes
+	cfgCaptureSize = "CaptureSize"
+	// cfgVerifyMetrics makes discovery of metrics run iostat to mark metrics it does not report
+	cfgVerifyMetrics = "VerifyMetrics"
+
+	// modeIostat runs iostat sampling statistics at each collection
+	modeIostat = "iostat"
+	// modeInterval computes statistics over the interval since the previous collection
+	modeInterval = "interval"
+)
+
+// defaults of config options, the sample window defaults to 1 second per sample
+const (
+	defaultSamples           = 1
+	defaultHealthUtil        = 90
+	defaultHealthAwaitHDD    = 50
+	defaultHealthAwaitSSD    = 5
+	defaultHealthQueueHDD    = 4
+	defaultHealthQueueSSD    = 32
+	defaultHealthQueueGrowth = 3
+	defaultAnomalyHalfLife   = 3600
+	defaultAnomalyWarmup     = 10
+	defaultTopDevicesBy      = "%util"
+	defaultReplayLoop        = true
+	// defaultCaptureSize is the default maximum size of the capture directory
+	defaultCaptureSize = 10 << 20
+)
+
+// configPolicy declares config options with their types and defaults; options
+// without a default are detected (HostRoot), derived (SampleWindow) or unset
+func configPolicy(prefix parser.Prefix) (*plugin.ConfigPolicy, error) {
+	c := plugin.NewConfigPolicy()
+	ns := []string{prefix.Vendor, prefix.Type}
+	for _, err := range []error{
+		c.AddNewBoolRule(ns, cfgReportSinceBoot, false, plugin.SetDefaultBool(false)),
+		c.AddNewStringRule(ns, cfgHostRoot, false),
+		c.AddNewIntRule(ns, cfgSamples, false, plugin.SetDefaultInt(defaultSamples), plugin.SetMinInt(1)),
+		c.AddNewIntRule(ns, cfgSampleWindow, false, plugin.SetMinInt(1)),
+		c.AddNewStringRule(ns, cfgMode, false, plugin.SetDefaultString(modeIostat)),
+		c.AddNewStringRule(ns, cfgTask, false),
+		c.AddNewFloatRule(ns, cfgHealthUtil, false, plugin.SetDefaultFloat(defaultHealthUtil)),
+		c.AddNewFloatRule(ns, cfgHealthAwaitHDD, false, plugin.SetDefaultFloat(defaultHealthAwaitHDD)),
+		c.AddNewFloatRule(ns, cfgHealthAwaitSSD, false, plugin.SetDefaultFloat(defaultHealthAwaitSSD)),
+		c.AddNewFloatRule(ns, cfgHealthQueueHDD, false, plugin.SetDefaultFloat(defaultHealthQueueHDD)),
+		c.AddNewFloatRule(ns, cfgHealthQueueSSD, false, plugin.SetDefaultFloat(defaultHealthQueueSSD)),
+		c.AddNewIntRule(ns, cfgHealthQueueGrowth, false, plugin.SetDefaultInt(defaultHealthQueueGrowth), plugin.SetMinInt(1)),
+		c.AddNewFloatRule(ns, cfgAnomalyHalfLife, false, plugin.SetDefaultFloat(defaultAnomalyHalfLife)),
+		c.AddNewIntRule(ns, cfgAnomalyWarmup, false, plugin.SetDefaultInt(defaultAnomalyWarmup), plugin.SetMinInt(0)),
+		c.AddNewIntRule(ns, cfgTopDevices, false, plugin.SetDefaultInt(0)),
+		c.AddNewStringRule(ns, cfgTopDevicesBy, false, plugin.SetDefaultString(defaultTopDevicesBy)),
+		c.AddNewBoolRule(ns, cfgHideNvmePaths, false, plugin.SetDefaultBool(false)),
+		c.AddNewStringRule(ns, cfgFilesystemTypes, false),
+		c.AddNewStringRule(ns, cfgUnits, false, plugin.SetDefaultString(unitKB)),
+		c.AddNewStringRule(ns, cfgNaming, false, plugin.SetDefaultString(parser.NamingLegacy)),
+		c.AddNewStringRule(ns, cfgReplay, false),
+		c.AddNewBoolRule(ns, cfgReplayLoop, false, plugin.SetDefaultBool(defaultReplayLoop)),
+		c.AddNewStringRule(ns, cfgCapture, false),
+		c.AddNewIntRule(ns, cfgCaptureSize, false, plugin.SetDefaultInt(defaultCaptureSize), plugin.SetMinInt(1)),
+		c.AddNewBoolRule(ns, cfgVerifyMetrics, false, plugin.SetDefaultBool(false)),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// validateConfig checks values of config options, so an invalid option fails
+// discovery and collection even if metrics using it are not requested
+func validateConfig(cfg plugin.Config) error {
+	if mode := getString(cfg, cfgMode, modeIostat); mode != modeIostat && mode != modeInterval {
+		return fmt.Errorf("Invalid mode %q (%s has to be %q or %q)", mode, cfgMode, modeIostat, modeInterval)
+	}
+	if _, _, err := getSampling(cfg); err != nil {
+		return err
+	}
+	if _, _, err := getThresholds(cfg); err != nil {
+		return err
+	}
+	if _, err := getUnit(cfg); err != nil {
+		return err
+	}
+	if _, err := getRanking(cfg); err != nil {
+		return err
+	}
+	for key, min := range map[string]int64{
+		cfgHealthQueueGrowth: 1,
+		cfgAnomalyWarmup:     0,
+		cfgCaptureSize:       1,
+	} {
+		if v := getInt(cfg, key, min); v < min {
+			return fmt.Errorf("Invalid %s %d (has to be at least %d)", key, v, min)
+		}
+	}
+	if v := getFloat(cfg, cfgAnomalyHalfLife, defaultAnomalyHalfLife); v <= 0 {
+		return fmt.Errorf("Invalid %s %v (has to be greater than 0)", cfgAnomalyHalfLife, v)
+	}
+	return nil
+}
+
+// configOf returns config of requested metrics, the config for each metric
+// being requested is the same so we need to check the config for one metric
+func configOf(mts []plugin.Metric) plugin.Config {
+	if len(mts) > 0 && mts[0].Config != nil {
+		return mts[0].Config
+	}
+	return plugin.Config{}
+}
+
+func getString(cfg plugin.Config, key string, def string) string {
+	if v, err := cfg.GetString(key); err == nil {
+		return v
+	}
+	return def
+}
+
+func getBool(cfg plugin.Config, key string, def bool) bool {
+	if v, err := cfg.GetBool(key); err == nil {
+		return v
+	}
+	return def
+}
+
+// getInt returns integer config value, Snap delivers numbers either as int64 or float64
+func getInt(cfg plugin.Config, key string, def int64) int64 {
+	if v, err := cfg.GetInt(key); err == nil {
+		return v
+	}
+	if v, err := cfg.GetFloat(key); err == nil {
+		return int64(v)
+	}
+	return def
+}
+
+// getFloat returns float config value, Snap delivers whole numbers as int64
+func getFloat(cfg plugin.Config, key string, def float64) float64 {
+	if v, err := cfg.GetFloat(key); err == nil {
+		return v
+	}
+	if v, err := cfg.GetInt(key); err == nil {
+		return float64(v)
+	}
+	return def
+}
@@ -0,0 +1,103 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpustat
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+)
+
+// indexes of fields in Stats, in order of columns of the cpu line in /proc/stat
+const (
+	User = iota
+	Nice
+	System
+	Idle
+	IOWait
+	IRQ
+	SoftIRQ
+	Steal
+
+	fieldsCount
+)
+
+// Stats holds time spent by all CPUs in each state since boot, in USER_HZ
+type Stats [fieldsCount]uint64
+
+// Read returns CPU statistics summed over all CPUs from /proc/stat
+func Read(fs *hostfs.FS) (Stats, error) {
+	var s Stats
+	file, err := os.Open(fs.Proc("stat"))
+	if err != nil {
+		return s, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "cpu" {
+			continue
+		}
+		// steal time is not reported by old kernels
+		for i := 0; i < fieldsCount && i+1 < len(fields); i++ {
+			s[i], err = strconv.ParseUint(fields[i+1], 10, 64)
+			if err != nil {
+				return s, err
+			}
+		}
+		return s, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return s, err
+	}
+	return s, errors.New("No cpu line found in /proc/stat")
+}
+
+// Utilization returns CPU utilization between two statistics, as reported by iostat -c
+func Utilization(prev, cur Stats) map[string]float64 {
+	var d Stats
+	total := 0.0
+	for i := range cur {
+		if cur[i] > prev[i] {
+			d[i] = cur[i] - prev[i]
+		}
+		total += float64(d[i])
+	}
+	percent := func(v uint64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(v) / total * 100
+	}
+	return map[string]float64{
+		"%user":   percent(d[User]),
+		"%nice":   percent(d[Nice]),
+		"%system": percent(d[System] + d[IRQ] + d[SoftIRQ]),
+		"%iowait": percent(d[IOWait]),
+		"%steal":  percent(d[Steal]),
+		"%idle":   percent(d[Idle]),
+	}
+}
@@ -0,0 +1,49 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpustat
+
+import (
+	"testing"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCpustat(t *testing.T) {
+	Convey("Given /proc/stat read CPU statistics", t, func() {
+		s, err := Read(hostfs.New("../testdata/host"))
+		So(err, ShouldBeNil)
+		So(s, ShouldResemble, Stats{4705, 356, 584, 3699176, 23060, 0, 277, 0})
+	})
+
+	Convey("Given two CPU statistics compute utilization", t, func() {
+		prev := Stats{100, 0, 100, 1000, 0, 0, 0, 0}
+		cur := Stats{150, 10, 120, 1300, 10, 5, 5, 0}
+		So(Utilization(prev, cur), ShouldResemble, map[string]float64{
+			"%user":   12.5,
+			"%nice":   2.5,
+			"%system": 7.5,
+			"%iowait": 2.5,
+			"%steal":  0,
+			"%idle":   75,
+		})
+		So(Utilization(cur, cur)["%idle"], ShouldEqual, 0)
+	})
+}
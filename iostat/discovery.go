@@ -0,0 +1,108 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iostat
+
+import (
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/diskstats"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/parser"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+// availableTag marks metric types which are not reported on this host
+const availableTag = "available"
+
+// deviceColumns returns names of device metrics reported in the mode selected
+// in config, without running iostat: in interval mode metrics computed from
+// /proc/diskstats, otherwise metrics reported by the installed version of
+// iostat or by any supported version if the version cannot be determined;
+// request sizes are followed by their canonical metrics in bytes
+func (iostat *Iostat) deviceColumns(cfg plugin.Config, unit string) []string {
+	var names []string
+	if getString(cfg, cfgMode, modeIostat) == modeInterval {
+		for name := range diskstats.Rates(diskstats.Stats{}, diskstats.Stats{}, 1) {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	} else {
+		version, err := iostat.parser.ParseVersion(iostat.runner(cfg).Exec("iostat", []string{"-V"}))
+		if err != nil {
+			log.WithField("error", err).Warn("cannot determine version of iostat, advertising metrics of all supported versions")
+			version = nil
+		}
+		names = deviceColumnsOf(version)
+	}
+
+	columns := []string{}
+	added := map[string]bool{}
+	for _, name := range names {
+		columns = append(columns, unitName(name, unit))
+		if size, ok := requestSizes[name]; ok && !added[size.name] {
+			added[size.name] = true
+			columns = append(columns, size.name)
+		}
+	}
+	return columns
+}
+
+// verify runs iostat and marks metric types of CPU and device metrics, named in
+// the naming scheme selected in config, which it does not report on this host
+// with tag "available" set to "false"; columns are given by their legacy names
+func (iostat *Iostat) verify(cfg plugin.Config, mts []plugin.Metric, columns []string) {
+	if getString(cfg, cfgMode, modeIostat) == modeInterval {
+		// metrics are computed by the plugin itself
+		return
+	}
+	reported := map[string]bool{}
+	namespaces, _, _, err := iostat.run(iostat.hostFS(cfg), []plugin.Metric{{Config: cfg}})
+	if err != nil {
+		log.WithField("error", err).Warn("cannot run iostat to verify metrics, marking its metrics unavailable")
+	}
+	for _, namespace := range namespaces {
+		elems := strings.Split(namespace, "/")
+		reported[elems[len(elems)-1]] = true
+	}
+
+	naming, err := getNaming(cfg)
+	if err != nil {
+		return
+	}
+	verified := map[string]bool{}
+	for _, name := range append(columns, cpuMetrics...) {
+		verified[parser.Rename(name, naming)] = true
+	}
+	cpuGroup := parser.Rename(cpuMetric, naming)
+	for i, mt := range mts {
+		ns := mt.Namespace
+		if ns[2].Value != cpuGroup && ns[2].Value != deviceMetric {
+			continue
+		}
+		name := ns[len(ns)-1].Value
+		if ns[2].Value == deviceMetric {
+			name = ns[4].Value
+		}
+		if verified[name] && !reported[name] {
+			mts[i].Tags = map[string]string{availableTag: "false"}
+		}
+	}
+}
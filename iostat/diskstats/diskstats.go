@@ -0,0 +1,156 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diskstats
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+)
+
+const (
+	// CounterMetric is a namespace element grouping raw cumulative counters of a device
+	CounterMetric = "counter"
+)
+
+// indexes of fields in Stats, in order of /proc/diskstats columns following the device name
+const (
+	ReadsCompleted = iota
+	ReadsMerged
+	SectorsRead
+	ReadTime
+	WritesCompleted
+	WritesMerged
+	SectorsWritten
+	WriteTime
+	InProgress
+	IOTicks
+	TimeInQueue
+
+	fieldsCount
+)
+
+// Counters lists names of cumulative counters by index of the field in Stats;
+// I/Os currently in progress is a gauge, so it has no name here
+var Counters = [fieldsCount]string{
+	ReadsCompleted:  "reads_completed",
+	ReadsMerged:     "reads_merged",
+	SectorsRead:     "sectors_read",
+	ReadTime:        "read_time_ms",
+	WritesCompleted: "writes_completed",
+	WritesMerged:    "writes_merged",
+	SectorsWritten:  "sectors_written",
+	WriteTime:       "write_time_ms",
+	IOTicks:         "io_ticks_ms",
+	TimeInQueue:     "time_in_queue_ms",
+}
+
+// Stats holds I/O statistics of a block device since boot
+type Stats [fieldsCount]uint64
+
+// Read returns statistics of all block devices listed in /proc/diskstats
+func Read(fs *hostfs.FS) (map[string]Stats, error) {
+	file, err := os.Open(fs.Proc("diskstats"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stats := map[string]Stats{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		dev, s, err := parseLine(scanner.Text())
+		if err != nil {
+			continue
+		}
+		stats[dev] = s
+	}
+	return stats, scanner.Err()
+}
+
+// parseLine parses line of /proc/diskstats, only the first 11 statistics are
+// used, fields added by newer kernels (discards, flushes) are ignored
+func parseLine(line string) (string, Stats, error) {
+	var s Stats
+	fields := strings.Fields(line)
+	if len(fields) < 3+fieldsCount {
+		return "", s, fmt.Errorf("Invalid format of diskstats line %q", line)
+	}
+	for i := range s {
+		v, err := strconv.ParseUint(fields[3+i], 10, 64)
+		if err != nil {
+			return "", s, err
+		}
+		s[i] = v
+	}
+	return fields[2], s, nil
+}
+
+// Rates returns extended statistics of a device, as reported by iostat -x -k,
+// computed from statistics taken seconds apart
+func Rates(prev, cur Stats, seconds float64) map[string]float64 {
+	var d Stats
+	for i := range cur {
+		d[i] = cur[i] - prev[i]
+	}
+	reads := float64(d[ReadsCompleted])
+	writes := float64(d[WritesCompleted])
+	ios := reads + writes
+
+	rates := map[string]float64{
+		"rrqm_per_sec": float64(d[ReadsMerged]) / seconds,
+		"wrqm_per_sec": float64(d[WritesMerged]) / seconds,
+		"r_per_sec":    reads / seconds,
+		"w_per_sec":    writes / seconds,
+		"rkB_per_sec":  float64(d[SectorsRead]) / 2 / seconds,
+		"wkB_per_sec":  float64(d[SectorsWritten]) / 2 / seconds,
+		"avgqu-sz":     float64(d[TimeInQueue]) / 1000 / seconds,
+		"avgrq-sz":     ratio(float64(d[SectorsRead]+d[SectorsWritten]), ios),
+		"await":        ratio(float64(d[ReadTime]+d[WriteTime]), ios),
+		"r_await":      ratio(float64(d[ReadTime]), reads),
+		"w_await":      ratio(float64(d[WriteTime]), writes),
+		"svctm":        ratio(float64(d[IOTicks]), ios),
+		"%util":        math.Min(float64(d[IOTicks])/10/seconds, 100),
+	}
+	return rates
+}
+
+// Decreased checks whether any counter of cur is lower than in prev, which means
+// the counters were reset and rates cannot be computed
+func Decreased(prev, cur Stats) bool {
+	for i := range cur {
+		if i != InProgress && cur[i] < prev[i] {
+			return true
+		}
+	}
+	return false
+}
+
+func ratio(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return a / b
+}
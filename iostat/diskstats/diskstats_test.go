@@ -0,0 +1,94 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diskstats
+
+import (
+	"math"
+	"testing"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRead(t *testing.T) {
+	Convey("Given /proc/diskstats read statistics of all devices", t, func() {
+		stats, err := Read(hostfs.New("../testdata/host"))
+		So(err, ShouldBeNil)
+		So(len(stats), ShouldEqual, 4)
+		So(stats["sda"], ShouldResemble, Stats{11906, 1431, 906770, 10816, 53124, 60453, 2658786, 89900, 0, 38728, 100716})
+		So(stats["dm-0"][ReadsCompleted], ShouldEqual, 4294967200)
+		So(stats["dm-0"][TimeInQueue], ShouldEqual, 308424)
+	})
+
+	Convey("Given invalid line return an error", t, func() {
+		_, _, err := parseLine("   8       1 sda1 11834 1431 902898 10784")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestTracker(t *testing.T) {
+	Convey("Given consecutive statistics extend wrapped counters", t, func() {
+		tracker := NewTracker()
+		s := Stats{math.MaxUint32 - 10, 5}
+		So(tracker.Update(map[string]Stats{"sda": s})["sda"], ShouldResemble, s)
+
+		// reads completed wrapped around at 32 bits
+		s = Stats{20, 6}
+		So(tracker.Update(map[string]Stats{"sda": s})["sda"][ReadsCompleted], ShouldEqual, uint64(math.MaxUint32)+21)
+		s = Stats{30, 7}
+		So(tracker.Update(map[string]Stats{"sda": s})["sda"][ReadsCompleted], ShouldEqual, uint64(math.MaxUint32)+31)
+
+		Convey("and start over when counters are reset", func() {
+			s = Stats{1, 0}
+			So(tracker.Update(map[string]Stats{"sda": s})["sda"], ShouldResemble, s)
+		})
+
+		Convey("and forget devices which disappeared", func() {
+			tracker.Update(map[string]Stats{})
+			s = Stats{3, 1}
+			So(tracker.Update(map[string]Stats{"sda": s})["sda"], ShouldResemble, s)
+		})
+	})
+}
+
+func TestRates(t *testing.T) {
+	Convey("Given statistics taken apart compute extended statistics", t, func() {
+		prev := Stats{100, 10, 1000, 50, 200, 20, 4000, 300, 1, 500, 1000}
+		cur := Stats{300, 30, 3000, 250, 400, 60, 8000, 900, 2, 1500, 3000}
+		So(Rates(prev, cur, 2), ShouldResemble, map[string]float64{
+			"rrqm_per_sec": 10,
+			"wrqm_per_sec": 20,
+			"r_per_sec":    100,
+			"w_per_sec":    100,
+			"rkB_per_sec":  500,
+			"wkB_per_sec":  1000,
+			"avgqu-sz":     1,
+			"avgrq-sz":     15,
+			"await":        2,
+			"r_await":      1,
+			"w_await":      3,
+			"svctm":        2.5,
+			"%util":        50,
+		})
+		So(Rates(cur, cur, 2)["await"], ShouldEqual, 0)
+		So(Decreased(prev, cur), ShouldBeFalse)
+		So(Decreased(cur, prev), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,92 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diskstats
+
+import (
+	"math"
+	"sync"
+)
+
+// wrap is the value at which 32-bit counters wrap around
+const wrap = math.MaxUint32 + 1
+
+// Tracker turns raw counters, which on older kernels (and for time fields on
+// any kernel) are 32-bit and wrap around, into monotonic 64-bit counters
+type Tracker struct {
+	mutex  sync.Mutex
+	last   map[string]Stats
+	offset map[string]Stats
+}
+
+// NewTracker returns tracker with no history
+func NewTracker() *Tracker {
+	return &Tracker{
+		last:   map[string]Stats{},
+		offset: map[string]Stats{},
+	}
+}
+
+// Update returns statistics with wrapped counters extended to 64 bits; history
+// of devices missing in stats is dropped, so a device added again starts over
+func (t *Tracker) Update(stats map[string]Stats) map[string]Stats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	extended := make(map[string]Stats, len(stats))
+	for dev, cur := range stats {
+		last, known := t.last[dev]
+		offset := t.offset[dev]
+		if known {
+			for i := range cur {
+				if i == InProgress || cur[i] >= last[i] {
+					continue
+				}
+				if wrapped(last[i], cur[i]) {
+					offset[i] += wrap
+				} else {
+					// counter was reset, e.g. by a driver reload
+					offset[i] = 0
+				}
+			}
+		}
+		t.last[dev] = cur
+		t.offset[dev] = offset
+
+		var e Stats
+		for i := range cur {
+			e[i] = cur[i] + offset[i]
+		}
+		extended[dev] = e
+	}
+
+	for dev := range t.last {
+		if _, ok := stats[dev]; !ok {
+			delete(t.last, dev)
+			delete(t.offset, dev)
+		}
+	}
+	return extended
+}
+
+// wrapped checks whether decrease of the counter is a 32-bit wraparound, in that
+// case the previous value fits in 32 bits and was in the upper half of the range
+func wrapped(last, cur uint64) bool {
+	return last <= math.MaxUint32 && last-cur > math.MaxUint32/2
+}
@@ -0,0 +1,144 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package filesystem lists mounted filesystems of the host and reads their
+// capacity and inode usage
+package filesystem
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+)
+
+const (
+	// FilesystemMetric is a namespace element grouping metrics of mounted filesystems
+	FilesystemMetric = "filesystem"
+)
+
+// Metrics lists names of metrics returned by Stat
+var Metrics = []string{
+	"bytes_total",
+	"bytes_used",
+	"bytes_available",
+	"inodes_total",
+	"inodes_used",
+}
+
+// Mount is a filesystem mounted on the host
+type Mount struct {
+	// Point is a mount point as seen by the host
+	Point string
+	// Source is a mounted device, e.g. /dev/sda1, or a remote share
+	Source string
+	// Device is a name of the block device resolved from the source, empty if the
+	// filesystem is not backed by a block device
+	Device string
+	Type   string
+}
+
+// Mounts returns filesystems mounted in the mount namespace of the host's init
+// process, falling back to the one of the plugin
+func Mounts(fs *hostfs.FS) ([]Mount, error) {
+	file, err := os.Open(fs.Proc("1", "mounts"))
+	if err != nil {
+		if file, err = os.Open(fs.Proc("self", "mounts")); err != nil {
+			return nil, err
+		}
+	}
+	defer file.Close()
+
+	mounts := []Mount{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		m := Mount{Source: unescape(fields[0]), Point: unescape(fields[1]), Type: fields[2]}
+		if strings.HasPrefix(m.Source, "/dev/") {
+			m.Device = device(fs, m.Source)
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts, scanner.Err()
+}
+
+// device resolves name of the block device, e.g. /dev/mapper/vg-root links to dm-0
+func device(fs *hostfs.FS, source string) string {
+	path := fs.Dev(strings.TrimPrefix(source, "/dev/"))
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return filepath.Base(path)
+}
+
+// unescape decodes octal escapes of whitespace and backslashes in /proc/[pid]/mounts, e.g. \040
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				out = append(out, byte(v))
+				i += 3
+				continue
+			}
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
+}
+
+// Escape turns the mount point into a single namespace element the way
+// systemd escapes paths, e.g. /var/lib/docker becomes var-lib-docker and / becomes -
+func Escape(path string) string {
+	parts := []string{}
+	for _, p := range strings.Split(path, "/") {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+
+	s := strings.Join(parts, "/")
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '/':
+			out = append(out, '-')
+		case c == '.' && i == 0,
+			!(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == ':' || c == '_' || c == '.'):
+			out = append(out, fmt.Sprintf(`\x%02x`, c)...)
+		default:
+			out = append(out, c)
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,66 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filesystem
+
+import (
+	"testing"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMounts(t *testing.T) {
+	Convey("Given mounts of the host list them resolving their devices", t, func() {
+		mounts, err := Mounts(hostfs.New("../testdata/host"))
+		So(err, ShouldBeNil)
+		So(mounts, ShouldHaveLength, 8)
+		So(mounts[0], ShouldResemble, Mount{Point: "/", Source: "/dev/sda1", Device: "sda1", Type: "ext4"})
+		So(mounts[1], ShouldResemble, Mount{Point: "/proc", Source: "proc", Type: "proc"})
+		So(mounts[4], ShouldResemble, Mount{Point: "/data", Source: "/dev/mapper/vg-data", Device: "dm-0", Type: "xfs"})
+		So(mounts[5].Point, ShouldEqual, "/mnt/backup disk")
+		So(mounts[7], ShouldResemble, Mount{Point: "/mnt/nfs", Source: "server:/export", Type: "nfs4"})
+	})
+}
+
+func TestEscape(t *testing.T) {
+	Convey("Given mount points escape them the way systemd does", t, func() {
+		So(Escape("/"), ShouldEqual, "-")
+		So(Escape("/var/lib/docker"), ShouldEqual, "var-lib-docker")
+		So(Escape("/var//lib/"), ShouldEqual, "var-lib")
+		So(Escape("/mnt/backup disk"), ShouldEqual, `mnt-backup\x20disk`)
+		So(Escape("/srv/my-data"), ShouldEqual, `srv-my\x2ddata`)
+		So(Escape("/.snapshots"), ShouldEqual, `\x2esnapshots`)
+	})
+}
+
+func TestStat(t *testing.T) {
+	Convey("Given mount point read capacity and inode usage of its filesystem", t, func() {
+		stat, err := Stat("/")
+		So(err, ShouldBeNil)
+		So(stat, ShouldHaveLength, len(Metrics))
+		So(stat["bytes_total"], ShouldBeGreaterThan, 0)
+		So(stat["bytes_used"], ShouldBeLessThanOrEqualTo, stat["bytes_total"])
+	})
+
+	Convey("Given missing mount point return an error", t, func() {
+		_, err := Stat("/nonexistent")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,41 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filesystem
+
+import "syscall"
+
+// Stat returns capacity and inode usage of the filesystem mounted at path
+func Stat(path string) (map[string]uint64, error) {
+	var s syscall.Statfs_t
+	if err := syscall.Statfs(path, &s); err != nil {
+		return nil, err
+	}
+	size := uint64(s.Frsize)
+	if size == 0 {
+		size = uint64(s.Bsize)
+	}
+	return map[string]uint64{
+		"bytes_total":     s.Blocks * size,
+		"bytes_used":      (s.Blocks - s.Bfree) * size,
+		"bytes_available": s.Bavail * size,
+		"inodes_total":    s.Files,
+		"inodes_used":     s.Files - s.Ffree,
+	}, nil
+}
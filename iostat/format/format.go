@@ -0,0 +1,183 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package format
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+const (
+	JSON   = "json"
+	CSV    = "csv"
+	Influx = "influx"
+)
+
+// Formats lists supported output formats
+var Formats = []string{JSON, CSV, Influx}
+
+type jsonMetric struct {
+	Namespace string            `json:"namespace"`
+	Data      interface{}       `json:"data"`
+	Unit      string            `json:"unit,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// Write writes metrics to w in the given format
+func Write(w io.Writer, format string, mts []plugin.Metric) error {
+	switch format {
+	case JSON:
+		return writeJSON(w, mts)
+	case CSV:
+		return writeCSV(w, mts)
+	case Influx:
+		return writeInflux(w, mts)
+	}
+	return fmt.Errorf("Unsupported output format %q (supported: %s)", format, strings.Join(Formats, ", "))
+}
+
+func writeJSON(w io.Writer, mts []plugin.Metric) error {
+	out := make([]jsonMetric, len(mts))
+	for i, mt := range mts {
+		out[i] = jsonMetric{
+			Namespace: mt.Namespace.String(),
+			Data:      mt.Data,
+			Unit:      mt.Unit,
+			Tags:      tags(mt),
+			Timestamp: mt.Timestamp,
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+func writeCSV(w io.Writer, mts []plugin.Metric) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"timestamp", "namespace", "data", "unit", "tags"})
+	for _, mt := range mts {
+		t := tags(mt)
+		pairs := make([]string, 0, len(t))
+		for _, k := range sortedKeys(t) {
+			pairs = append(pairs, k+"="+t[k])
+		}
+		writer.Write([]string{
+			mt.Timestamp.Format(time.RFC3339Nano),
+			mt.Namespace.String(),
+			fmt.Sprint(mt.Data),
+			mt.Unit,
+			strings.Join(pairs, ";"),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeInflux writes metrics in InfluxDB line protocol, the measurement is the
+// namespace without dynamic elements, which are written as tags
+func writeInflux(w io.Writer, mts []plugin.Metric) error {
+	for _, mt := range mts {
+		value, ok := influxValue(mt.Data)
+		if !ok {
+			continue
+		}
+		parts := []string{}
+		for _, e := range mt.Namespace {
+			if !e.IsDynamic() {
+				parts = append(parts, e.Value)
+			}
+		}
+		line := escapeInflux("/"+strings.Join(parts, "/"), false)
+		t := tags(mt)
+		for _, k := range sortedKeys(t) {
+			line += "," + escapeInflux(k, true) + "=" + escapeInflux(t[k], true)
+		}
+		if _, err := fmt.Fprintf(w, "%s value=%s %d\n", line, value, mt.Timestamp.UnixNano()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tags returns tags of the metric completed with values of dynamic elements
+func tags(mt plugin.Metric) map[string]string {
+	t := map[string]string{}
+	for k, v := range mt.Tags {
+		t[k] = v
+	}
+	for _, e := range mt.Namespace {
+		if e.IsDynamic() {
+			t[e.Name] = e.Value
+		}
+	}
+	return t
+}
+
+func influxValue(data interface{}) (string, bool) {
+	switch v := data.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), true
+	case int:
+		return strconv.Itoa(v) + "i", true
+	case int64:
+		return strconv.FormatInt(v, 10) + "i", true
+	case uint64:
+		if v > math.MaxInt64 {
+			return strconv.FormatFloat(float64(v), 'f', -1, 64), true
+		}
+		return strconv.FormatUint(v, 10) + "i", true
+	case bool:
+		return strconv.FormatBool(v), true
+	case string:
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`, true
+	}
+	return "", false
+}
+
+// escapeInflux escapes special characters of measurement (commas and spaces)
+// or of tag keys and values (additionally equal signs)
+func escapeInflux(s string, tag bool) string {
+	s = strings.NewReplacer(",", `\,`, " ", `\ `).Replace(s)
+	if tag {
+		s = strings.Replace(s, "=", `\=`, -1)
+	}
+	return s
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
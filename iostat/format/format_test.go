@@ -0,0 +1,86 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var timestamp = time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func mockMetrics() []plugin.Metric {
+	ns := plugin.NewNamespace("intel", "iostat", "device").
+		AddDynamicElement("device_id", "Device ID").
+		AddStaticElement("await")
+	ns[3].Value = "sda"
+	return []plugin.Metric{
+		plugin.Metric{
+			Namespace: ns,
+			Data:      1.5,
+			Tags:      map[string]string{"dev": "sda"},
+			Timestamp: timestamp,
+		},
+		plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "iostat", "avg-cpu", "%idle"),
+			Data:      99.0,
+			Timestamp: timestamp,
+		},
+	}
+}
+
+func TestFormat(t *testing.T) {
+	Convey("Given metrics write them in JSON", t, func() {
+		buf := &bytes.Buffer{}
+		So(Write(buf, JSON, mockMetrics()), ShouldBeNil)
+
+		out := []jsonMetric{}
+		So(json.Unmarshal(buf.Bytes(), &out), ShouldBeNil)
+		So(len(out), ShouldEqual, 2)
+		So(out[0].Namespace, ShouldEqual, "/intel/iostat/device/sda/await")
+		So(out[0].Data, ShouldEqual, 1.5)
+		So(out[0].Tags, ShouldResemble, map[string]string{"dev": "sda", "device_id": "sda"})
+		So(out[0].Timestamp.Equal(timestamp), ShouldBeTrue)
+	})
+
+	Convey("Given metrics write them in CSV", t, func() {
+		buf := &bytes.Buffer{}
+		So(Write(buf, CSV, mockMetrics()), ShouldBeNil)
+		So(buf.String(), ShouldEqual, "timestamp,namespace,data,unit,tags\n"+
+			"2017-03-01T12:00:00Z,/intel/iostat/device/sda/await,1.5,,dev=sda;device_id=sda\n"+
+			"2017-03-01T12:00:00Z,/intel/iostat/avg-cpu/%idle,99,,\n")
+	})
+
+	Convey("Given metrics write them in InfluxDB line protocol", t, func() {
+		buf := &bytes.Buffer{}
+		So(Write(buf, Influx, mockMetrics()), ShouldBeNil)
+		So(buf.String(), ShouldEqual, "/intel/iostat/device/await,dev=sda,device_id=sda value=1.5 1488369600000000000\n"+
+			"/intel/iostat/avg-cpu/%idle value=99 1488369600000000000\n")
+	})
+
+	Convey("Given unknown format return an error", t, func() {
+		So(Write(&bytes.Buffer{}, "xml", mockMetrics()), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,231 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iostat
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/parser"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/sysfs"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+const (
+	// healthMetric is a namespace element grouping health classification of a device
+	healthMetric = "health"
+
+	// latency classes of a device
+	latencyNormal   = 0
+	latencyElevated = 1
+	latencyHigh     = 2
+
+	// latencyElevatedRatio is a fraction of the await threshold above which latency is elevated
+	latencyElevatedRatio = 0.5
+
+	// queue trends of devices not seen for queueTrendExpiry are forgotten
+	queueTrendExpiry = 10 * time.Minute
+)
+
+// healthMetrics lists names of metrics returned by health and of the queue trend
+var healthMetrics = []string{"saturation_score", "saturated", "latency_class", "queue_growing"}
+
+// queueTrend follows average queue size of a device between collections
+type queueTrend struct {
+	size float64
+	// growth is a number of consecutive collections in which the queue grew
+	growth  int64
+	updated time.Time
+}
+
+// thresholds of device utilization, latency and queue size
+type thresholds struct {
+	util  float64
+	await float64
+	queue float64
+}
+
+// getThresholds returns thresholds for rotational and non-rotational devices,
+// thresholds have to be positive as values are scored relative to them
+func getThresholds(cfg plugin.Config) (hdd, ssd thresholds, err error) {
+	values := map[string]float64{}
+	for key, def := range map[string]float64{
+		cfgHealthUtil:     defaultHealthUtil,
+		cfgHealthAwaitHDD: defaultHealthAwaitHDD,
+		cfgHealthAwaitSSD: defaultHealthAwaitSSD,
+		cfgHealthQueueHDD: defaultHealthQueueHDD,
+		cfgHealthQueueSSD: defaultHealthQueueSSD,
+	} {
+		values[key] = getFloat(cfg, key, def)
+		if values[key] <= 0 {
+			return hdd, ssd, fmt.Errorf("Invalid %s %v (has to be greater than 0)", key, values[key])
+		}
+	}
+	hdd = thresholds{
+		util:  values[cfgHealthUtil],
+		await: values[cfgHealthAwaitHDD],
+		queue: values[cfgHealthQueueHDD],
+	}
+	ssd = thresholds{
+		util:  values[cfgHealthUtil],
+		await: values[cfgHealthAwaitSSD],
+		queue: values[cfgHealthQueueSSD],
+	}
+	return hdd, ssd, nil
+}
+
+// addHealth adds health classification of each device; thresholds for
+// rotational disks are used when the kind of the device cannot be determined
+func (iostat *Iostat) addHealth(fs *hostfs.FS, cfg plugin.Config, data map[string]interface{}, naming string) error {
+	hdd, ssd, err := getThresholds(cfg)
+	if err != nil {
+		return err
+	}
+	growth := getInt(cfg, cfgHealthQueueGrowth, defaultHealthQueueGrowth)
+	if growth < 1 {
+		return fmt.Errorf("Invalid %s %d (has to be at least 1)", cfgHealthQueueGrowth, growth)
+	}
+
+	groups := groupDevices(data)
+	queues := map[string]float64{}
+	for _, dev := range sortedDevices(groups) {
+		t := hdd
+		if rotational, err := sysfs.IsRotational(fs, dev); err == nil && !rotational {
+			t = ssd
+		} else if err != nil {
+			log.WithFields(log.Fields{
+				"device": dev,
+				"error":  err,
+			}).Debug("cannot determine whether device is rotational")
+		}
+		values := deviceValues(data, groups[dev], naming)
+		for name, v := range health(t, values) {
+			data[iostat.deviceNamespace(dev, healthMetric, name)] = v
+		}
+		queues[dev] = queueSize(values)
+	}
+	for dev, n := range iostat.queueGrowth(getString(cfg, cfgTask, ""), queues, time.Now()) {
+		data[iostat.deviceNamespace(dev, healthMetric, "queue_growing")] = n >= growth
+	}
+	return nil
+}
+
+// queueGrowth updates trends of queue sizes of devices, kept per task given in
+// config, and returns numbers of consecutive collections in which they grew
+func (iostat *Iostat) queueGrowth(task string, queues map[string]float64, now time.Time) map[string]int64 {
+	iostat.mutex.Lock()
+	defer iostat.mutex.Unlock()
+	if iostat.queues == nil {
+		iostat.queues = map[string]*queueTrend{}
+	}
+
+	growth := map[string]int64{}
+	for dev, size := range queues {
+		key := task + " " + dev
+		trend, ok := iostat.queues[key]
+		if !ok {
+			trend = &queueTrend{size: size}
+			iostat.queues[key] = trend
+		} else if size > trend.size {
+			trend.growth++
+		} else {
+			trend.growth = 0
+		}
+		trend.size = size
+		trend.updated = now
+		growth[dev] = trend.growth
+	}
+	for key, trend := range iostat.queues {
+		if now.Sub(trend.updated) > queueTrendExpiry {
+			delete(iostat.queues, key)
+		}
+	}
+	return growth
+}
+
+// health classifies the device based on its metrics; the saturation score is
+// the highest of %util, await and average queue size relative to their
+// thresholds, so the device is saturated when the score reaches 1
+func health(t thresholds, values map[string]float64) map[string]interface{} {
+	await := deviceAwait(values)
+	queue := queueSize(values)
+
+	score := math.Max(values["%util"]/t.util, math.Max(await/t.await, queue/t.queue))
+
+	class := int64(latencyNormal)
+	switch {
+	case await >= t.await:
+		class = latencyHigh
+	case await >= t.await*latencyElevatedRatio:
+		class = latencyElevated
+	}
+
+	return map[string]interface{}{
+		"saturation_score": score,
+		"saturated":        score >= 1,
+		"latency_class":    class,
+	}
+}
+
+// deviceAwait returns average time of I/O requests of the device
+func deviceAwait(values map[string]float64) float64 {
+	if await, ok := values["await"]; ok {
+		return await
+	}
+	// sysstat 12 reports latency of reads and writes only
+	return weighted(values["r_await"], values["r_per_sec"], values["w_await"], values["w_per_sec"])
+}
+
+// queueSize returns average queue size of the device, named differently by versions of sysstat
+func queueSize(values map[string]float64) float64 {
+	if queue, ok := values["avgqu-sz"]; ok {
+		return queue
+	}
+	return values["aqu-sz"]
+}
+
+// deviceValues returns numeric metrics of the device by their legacy names,
+// keys are namespaces of metrics of the device, named in the naming scheme,
+// as grouped by groupDevices
+func deviceValues(data map[string]interface{}, keys []string, naming string) map[string]float64 {
+	values := make(map[string]float64, len(keys))
+	for _, k := range keys {
+		f, ok := data[k].(float64)
+		if !ok {
+			continue
+		}
+		_, name, _ := deviceKey(k)
+		for _, legacy := range parser.Legacy(name, naming) {
+			values[legacy] = f
+		}
+	}
+	return values
+}
+
+// weighted returns mean of a and b weighted by wa and wb
+func weighted(a, wa, b, wb float64) float64 {
+	if wa+wb == 0 {
+		return 0
+	}
+	return (a*wa + b*wb) / (wa + wb)
+}
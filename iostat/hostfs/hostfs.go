@@ -0,0 +1,98 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostfs
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// candidates are directories where host root filesystem is commonly mounted
+// when the plugin runs inside a container
+var candidates = []string{"/host", "/hostfs", "/rootfs"}
+
+// FS resolves paths of host's /proc, /sys and /dev, and of host's mount points
+type FS struct {
+	root string
+	proc string
+	sys  string
+	dev  string
+}
+
+// New returns FS for the host root filesystem mounted at root; when root is empty
+// it is detected by looking for a host proc mounted in one of the well known places
+func New(root string) *FS {
+	if root == "" {
+		root = Detect()
+	}
+	return &FS{
+		root: root,
+		proc: mounted(root, "proc"),
+		sys:  mounted(root, "sys"),
+		dev:  mounted(root, "dev"),
+	}
+}
+
+// Detect returns directory where host root filesystem is mounted or "/"
+// if no host proc is found
+func Detect() string {
+	for _, c := range candidates {
+		if _, err := os.Stat(filepath.Join(c, "proc", "diskstats")); err == nil {
+			return c
+		}
+	}
+	return "/"
+}
+
+// mounted returns path of the given pseudo filesystem under root, falling back
+// to the one seen by the plugin when it is not mounted there (for example only
+// host /proc is bind mounted into the container)
+func mounted(root, name string) string {
+	path := filepath.Join(root, name)
+	if _, err := os.Stat(path); err != nil {
+		return filepath.Join("/", name)
+	}
+	return path
+}
+
+// Root returns directory where host root filesystem is mounted
+func (fs *FS) Root() string {
+	return fs.root
+}
+
+// Path returns path of the host's file, e.g. a mount point
+func (fs *FS) Path(elem ...string) string {
+	return filepath.Join(append([]string{fs.root}, elem...)...)
+}
+
+// Proc returns path of the file in host's /proc
+func (fs *FS) Proc(elem ...string) string {
+	return filepath.Join(append([]string{fs.proc}, elem...)...)
+}
+
+// Sys returns path of the file in host's /sys
+func (fs *FS) Sys(elem ...string) string {
+	return filepath.Join(append([]string{fs.sys}, elem...)...)
+}
+
+// Dev returns path of the file in host's /dev
+func (fs *FS) Dev(elem ...string) string {
+	return filepath.Join(append([]string{fs.dev}, elem...)...)
+}
@@ -0,0 +1,64 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHostFS(t *testing.T) {
+	Convey("Given host root resolve paths under it", t, func() {
+		dir, err := ioutil.TempDir("", "hostfs")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		So(os.MkdirAll(filepath.Join(dir, "proc"), 0755), ShouldBeNil)
+
+		fs := New(dir)
+		So(fs.Root(), ShouldEqual, dir)
+		So(fs.Proc("diskstats"), ShouldEqual, filepath.Join(dir, "proc", "diskstats"))
+		So(fs.Path("var", "lib"), ShouldEqual, filepath.Join(dir, "var", "lib"))
+
+		Convey("falling back to own /sys and /dev if they are not mounted", func() {
+			So(fs.Sys("block"), ShouldEqual, "/sys/block")
+			So(fs.Dev("sda"), ShouldEqual, "/dev/sda")
+		})
+	})
+
+	Convey("Given mounted host proc detect host root", t, func() {
+		dir, err := ioutil.TempDir("", "hostfs")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		saved := candidates
+		defer func() { candidates = saved }()
+		candidates = []string{filepath.Join(dir, "host")}
+		So(Detect(), ShouldEqual, "/")
+
+		So(os.MkdirAll(filepath.Join(dir, "host", "proc"), 0755), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "host", "proc", "diskstats"), []byte{}, 0644), ShouldBeNil)
+		So(Detect(), ShouldEqual, filepath.Join(dir, "host"))
+		So(New("").Root(), ShouldEqual, filepath.Join(dir, "host"))
+	})
+}
@@ -0,0 +1,161 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iostat
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/cpustat"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/diskstats"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/parser"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/sysfs"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+const (
+	cpuMetric = "avg-cpu"
+	allDevice = "ALL"
+
+	// snapshots not refreshed for snapshotExpiry intervals of their collections,
+	// and at least for minSnapshotTTL, are forgotten, e.g. of tasks which were stopped
+	snapshotExpiry = 10
+	minSnapshotTTL = 10 * time.Minute
+)
+
+// snapshot holds counters taken at a collection
+type snapshot struct {
+	taken time.Time
+	// interval since the previous snapshot, zero for the first one
+	interval time.Duration
+	disks    map[string]diskstats.Stats
+	cpu      cpustat.Stats
+}
+
+// collectInterval computes statistics over the interval since the previous
+// collection of the same metrics from counters in /proc/diskstats and /proc/stat,
+// returning immediately instead of sampling; on the first collection there is
+// no previous snapshot, so no statistics are returned. Statistics are named as
+// iostat names them, in the naming scheme.
+func (iostat *Iostat) collectInterval(fs *hostfs.FS, mts []plugin.Metric, naming string) (map[string]interface{}, error) {
+	disks, err := diskstats.Read(fs)
+	if err != nil {
+		return nil, err
+	}
+	cpu, err := cpustat.Read(fs)
+	if err != nil {
+		return nil, err
+	}
+	cur := &snapshot{taken: time.Now(), disks: iostat.counters.Update(disks), cpu: cpu}
+
+	// snapshots are kept per task and set of requested metrics, tasks requesting
+	// the same metrics have to be told apart by config, so that tasks with different
+	// intervals do not shorten intervals of each other
+	key := snapshotKey(mts)
+	iostat.mutex.Lock()
+	prev := iostat.snapshots[key]
+	if prev != nil {
+		cur.interval = cur.taken.Sub(prev.taken)
+	}
+	iostat.snapshots[key] = cur
+	iostat.expireSnapshots(cur.taken)
+	iostat.mutex.Unlock()
+
+	data := map[string]interface{}{}
+	if prev == nil {
+		log.Info("first collection in interval mode, statistics will be available from the next collection")
+		return data, nil
+	}
+	seconds := cur.taken.Sub(prev.taken).Seconds()
+	if seconds <= 0 {
+		return data, nil
+	}
+
+	for name, v := range cpustat.Utilization(prev.cpu, cur.cpu) {
+		data[iostat.joinNamespace(parser.Rename(cpuMetric, naming), parser.Rename(name, naming))] = v
+	}
+
+	var allPrev, allCur diskstats.Stats
+	whole := 0
+	for dev, c := range cur.disks {
+		p, ok := prev.disks[dev]
+		if !ok {
+			// a new device, rates will be available from the next collection
+			continue
+		}
+		if diskstats.Decreased(p, c) {
+			log.WithField("device", dev).Info("device counters were reset, skipping it in this collection")
+			continue
+		}
+		for name, v := range diskstats.Rates(p, c, seconds) {
+			data[iostat.deviceNamespace(dev, parser.Rename(name, naming))] = v
+		}
+		if !sysfs.IsPartition(fs, dev) {
+			for i := range c {
+				allPrev[i] += p[i]
+				allCur[i] += c[i]
+			}
+			whole++
+		}
+	}
+	if whole > 0 {
+		for name, v := range diskstats.Rates(allPrev, allCur, seconds) {
+			if name == "%util" {
+				// utilization of the group is the average utilization of its devices
+				v = diskstats.Rates(allPrev, allCur, seconds*float64(whole))[name]
+			}
+			data[iostat.deviceNamespace(allDevice, parser.Rename(name, naming))] = v
+		}
+	}
+	return data, nil
+}
+
+// snapshotKey identifies the task given in config and set of requested metrics
+func snapshotKey(mts []plugin.Metric) string {
+	namespaces := make([]string, len(mts))
+	for i, mt := range mts {
+		namespaces[i] = mt.Namespace.String()
+	}
+	sort.Strings(namespaces)
+	return getString(configOf(mts), cfgTask, "") + " " + strings.Join(namespaces, " ")
+}
+
+// expireSnapshots removes snapshots which were not refreshed for a long time
+// relative to intervals of their collections, so snapshots of requests which
+// are not repeated do not accumulate; the mutex has to be held
+func (iostat *Iostat) expireSnapshots(now time.Time) {
+	for key, s := range iostat.snapshots {
+		ttl := snapshotExpiry * s.interval
+		if ttl < minSnapshotTTL {
+			ttl = minSnapshotTTL
+		}
+		if now.Sub(s.taken) > ttl {
+			delete(iostat.snapshots, key)
+		}
+	}
+}
+
+// joinNamespace returns namespace of the metric as a string
+func (iostat *Iostat) joinNamespace(elems ...string) string {
+	return plugin.NewNamespace(iostat.prefix.Vendor, iostat.prefix.Type).AddStaticElements(elems...).String()
+}
@@ -22,27 +22,39 @@ package iostat
 import (
 	"fmt"
 	"io"
-	"os"
-	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/baseline"
 	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/command"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/diskstats"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/filesystem"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/mdraid"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/nvme"
 	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/parser"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/sysfs"
 	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
 )
 
 const (
 	deviceMetric = "device"
+
+	// cmdTimeout is time allowed for iostat on top of the sampling window
+	cmdTimeout = 2 * time.Second
 )
 
 type runsCmd interface {
-	Run(cmd string, args []string) (io.Reader, error)
+	Run(cmd string, args []string, timeout time.Duration) (io.Reader, error)
 	Exec(cmd string, args []string) string
 }
 
 type parses interface {
-	Parse(io.Reader) ([]string, map[string]float64, error)
+	Parse(reader io.Reader, prefix parser.Prefix, naming string) ([]string, []map[string]float64, error)
 	ParseVersion(string) ([]int64, error)
 }
 
@@ -50,24 +62,75 @@ type parses interface {
 type Iostat struct {
 	cmd    runsCmd
 	parser parses
+	// prefix holds the first two elements of namespaces of all metrics
+	prefix parser.Prefix
+	// roots holds resolved host filesystems by host root given in config, so
+	// detection of the host root does not run at each collection
+	roots map[string]*hostfs.FS
+	// counters extends wrapping counters read from /proc/diskstats
+	counters *diskstats.Tracker
+	// baseline holds rolling statistics of device metrics scored for anomalies
+	baseline *baseline.Baseline
+
+	mutex sync.Mutex
+	// snapshots of counters taken at the previous collection in interval mode
+	snapshots map[string]*snapshot
+	// runners replaying or capturing output of iostat by their config, used instead of cmd if selected
+	runners map[string]runsCmd
+	// queues holds trends of queue sizes of devices by task and device
+	queues map[string]*queueTrend
 }
 
-// NewIostatCollector returns instance of iostat object
-func NewIostatCollector() *Iostat {
+// NewIostatCollector returns instance of iostat object publishing metrics under the prefix
+func NewIostatCollector(prefix parser.Prefix) *Iostat {
 	return &Iostat{
-		cmd:    command.New(),
-		parser: parser.New(),
+		cmd:       command.New(),
+		parser:    parser.New(),
+		prefix:    prefix,
+		counters:  diskstats.NewTracker(),
+		baseline:  baseline.New(),
+		snapshots: map[string]*snapshot{},
 	}
 }
 
 // CollectMetrics returns metrics from iostat
 func (iostat *Iostat) CollectMetrics(mts []plugin.Metric) ([]plugin.Metric, error) {
-	_, data, err := iostat.run(mts)
+	if err := validateConfig(configOf(mts)); err != nil {
+		return nil, err
+	}
+	// metrics are collected named in the naming scheme of requested metrics
+	naming, err := getNaming(configOf(mts))
 	if err != nil {
 		return nil, err
 	}
 
+	fs := iostat.hostFS(configOf(mts))
+	_, data, reported, err := iostat.run(fs, mts)
+	if err != nil {
+		return nil, err
+	}
+	if getBool(configOf(mts), cfgHideNvmePaths, false) {
+		hideNvmePaths(data)
+	}
+	ranks, err := topDevices(configOf(mts), data, reported)
+	if err != nil {
+		return nil, err
+	}
+	var fsTags map[string]map[string]string
+	if isRequestedGroup(mts, filesystem.FilesystemMetric) {
+		// with top N devices only filesystems of the ranked devices are collected
+		backing := reported
+		if ranks != nil {
+			backing = devices(data)
+		}
+		if fsTags, err = iostat.addFilesystems(fs, configOf(mts), data, backing); err != nil {
+			return nil, err
+		}
+	}
+	devTags := deviceTags(fs, mts, data, ranks)
+
 	metrics := []plugin.Metric{}
+	idx := newIndex(data)
 
 	for _, mt := range mts {
 		ns := mt.Namespace
@@ -75,127 +138,354 @@ func (iostat *Iostat) CollectMetrics(mts []plugin.Metric) ([]plugin.Metric, erro
 			return nil, fmt.Errorf("Namespace length is too short (len = %d)", len(ns))
 		}
 
-		if ns[3].Value == "*" {
-			if ns[2].Value == deviceMetric {
-				for k := range data {
-					reg := ns[:2].String() + "/device/.*" + ns[4:].String()
-					matched, err := regexp.MatchString(reg, k)
-					if !matched {
-						continue
-					}
-					if err != nil {
-						return nil, fmt.Errorf("Error matching namespaces %v", ns)
-					}
-
-					dev, err := extractFromNamespace(k, 3)
-					if err != nil {
-						return nil, err
-					}
-
-					nsCopy := plugin.CopyNamespace(ns)
-					nsCopy[3].Value = dev
-
-					if v, ok := data[nsCopy.String()]; ok {
-						metrics = append(metrics, plugin.Metric{
-							Namespace: nsCopy,
-							Data:      v,
-							Timestamp: time.Now(),
-							Tags:      map[string]string{"dev": dev}})
-					} else {
-						fmt.Fprintf(os.Stdout, "No data found for metric %v", ns.Strings())
-					}
+		matched := idx.match(ns)
+		if len(matched) == 0 {
+			// stdout is left to output of the plugin, e.g. metrics dumped by collect
+			log.WithField("namespace", ns.String()).Debug("no data found for metric")
+			continue
+		}
+		for _, nsMatched := range matched {
+			metric := plugin.Metric{
+				Namespace: nsMatched,
+				Data:      data[nsMatched.String()],
+				Timestamp: time.Now()}
+			switch nsMatched[2].Value {
+			case deviceMetric:
+				dev := nsMatched[3].Value
+				if isPattern(ns[3].Value) {
+					metric.Tags = map[string]string{"dev": dev}
 				}
-			} else {
-				return nil, fmt.Errorf("Dynamic option * not supported for metric %v", ns)
-			}
-		} else {
-			if v, ok := data[mt.Namespace.String()]; ok {
-				metrics = append(metrics, plugin.Metric{
-					Namespace: mt.Namespace,
-					Data:      v,
-					Timestamp: time.Now()})
-			} else {
-				fmt.Fprintf(os.Stdout, "No data found for metric %v", ns.Strings())
+				metric.Tags = withTags(metric.Tags, devTags[dev])
+			case filesystem.FilesystemMetric:
+				metric.Tags = withTags(nil, fsTags[nsMatched[3].Value])
 			}
+			metrics = append(metrics, metric)
 		}
 	}
 
+	for i := range metrics {
+		describe(&metrics[i], naming)
+	}
 	return metrics, nil
 }
 
 // GetMetricTypes returns the metric types exposed by iostat
-func (iostat *Iostat) GetMetricTypes(_ plugin.Config) ([]plugin.Metric, error) {
-	namespaces, _, err := iostat.run([]plugin.Metric{})
+func (iostat *Iostat) GetMetricTypes(cfg plugin.Config) ([]plugin.Metric, error) {
+	if err := validateConfig(cfg); err != nil {
+		return nil, err
+	}
+	// names of throughput metrics depend on the unit selected in config
+	unit, err := getUnit(cfg)
+	if err != nil {
+		return nil, err
+	}
+	naming, err := getNaming(cfg)
 	if err != nil {
 		return nil, err
 	}
 
 	mts := []plugin.Metric{}
-	metric := plugin.Metric{}
-	// List of terminal metric names
-	mList := make(map[string]bool)
-	for _, namespace := range namespaces {
-		ns := plugin.NewNamespace(strings.Split(strings.TrimPrefix(namespace, "/"), "/")...)
-
-		if len(ns) < 4 {
-			return nil, fmt.Errorf("Namespace length is too short (len = %d)", len(ns))
+	for _, name := range cpuMetrics {
+		mts = append(mts, plugin.Metric{Namespace: plugin.NewNamespace(iostat.prefix.Vendor, iostat.prefix.Type, cpuMetric, name)})
+	}
+	columns := iostat.deviceColumns(cfg, unit)
+	// statistics over samples exist only if more samples are taken by iostat
+	withStats := getInt(cfg, cfgSamples, defaultSamples) > 1 && getString(cfg, cfgMode, modeIostat) == modeIostat
+	for _, name := range columns {
+		metric := plugin.Metric{
+			Namespace: plugin.NewNamespace(iostat.prefix.Vendor, iostat.prefix.Type, deviceMetric).
+				AddDynamicElement("device_id", "Device ID").
+				AddStaticElement(name),
 		}
-		// terminal metric name
-		mItem := ns[len(ns)-1]
-		if ns[2].Value == deviceMetric {
-			if !mList[mItem.Value] {
-				mList[mItem.Value] = true
-				metric = plugin.Metric{
-					Namespace: plugin.NewNamespace(parser.NsVendor, parser.NsType, deviceMetric).
-						AddDynamicElement("device_id", "Device ID").
-						AddStaticElement(mItem.Value),
-					Description: "dynamic device metric: " + mItem.Value}
-			} else {
-				continue
+		// statistics over samples taken in the sample window
+		if withStats {
+			for _, stat := range sampleStats {
+				mts = append(mts, plugin.Metric{Namespace: plugin.CopyNamespace(metric.Namespace).AddStaticElement(stat)})
 			}
-		} else {
-			metric = plugin.Metric{Namespace: ns}
 		}
-
 		mts = append(mts, metric)
 	}
 
-	return mts, nil
+	for _, name := range diskstats.Counters {
+		if name == "" {
+			continue
+		}
+		mts = append(mts, plugin.Metric{
+			Namespace: plugin.NewNamespace(iostat.prefix.Vendor, iostat.prefix.Type, deviceMetric).
+				AddDynamicElement("device_id", "Device ID").
+				AddStaticElements(diskstats.CounterMetric, name),
+		})
+	}
+	for _, name := range sysfs.QueueMetrics {
+		mts = append(mts, plugin.Metric{
+			Namespace: plugin.NewNamespace(iostat.prefix.Vendor, iostat.prefix.Type, deviceMetric).
+				AddDynamicElement("device_id", "Device ID").
+				AddStaticElements(sysfs.QueueMetric, name),
+		})
+	}
+	for _, name := range filesystem.Metrics {
+		mts = append(mts, plugin.Metric{
+			Namespace: plugin.NewNamespace(iostat.prefix.Vendor, iostat.prefix.Type, filesystem.FilesystemMetric).
+				AddDynamicElement("mount", "Mount point, escaped like systemd does (e.g. var-lib)").
+				AddStaticElement(name),
+		})
+	}
+	for _, name := range sysfs.ZramMetrics {
+		mts = append(mts, plugin.Metric{
+			Namespace: plugin.NewNamespace(iostat.prefix.Vendor, iostat.prefix.Type, deviceMetric).
+				AddDynamicElement("device_id", "Device ID").
+				AddStaticElements(sysfs.ZramMetric, name),
+		})
+	}
+	for _, name := range anomalyMetrics {
+		name = unitName(name, unit)
+		mts = append(mts, plugin.Metric{
+			Namespace: plugin.NewNamespace(iostat.prefix.Vendor, iostat.prefix.Type, deviceMetric).
+				AddDynamicElement("device_id", "Device ID").
+				AddStaticElements(anomalyMetric, name),
+		})
+	}
+	for _, name := range mdraid.Metrics {
+		name = unitName(name, unit)
+		mts = append(mts, plugin.Metric{
+			Namespace: plugin.NewNamespace(iostat.prefix.Vendor, iostat.prefix.Type, deviceMetric).
+				AddDynamicElement("device_id", "Device ID").
+				AddStaticElements(mdraid.MdMetric, name),
+		})
+	}
+	for _, name := range healthMetrics {
+		mts = append(mts, plugin.Metric{
+			Namespace: plugin.NewNamespace(iostat.prefix.Vendor, iostat.prefix.Type, deviceMetric).
+				AddDynamicElement("device_id", "Device ID").
+				AddStaticElements(healthMetric, name),
+		})
+	}
+
+	renameMetrics(mts, naming)
+	if getBool(cfg, cfgVerifyMetrics, false) {
+		iostat.verify(cfg, mts, columns)
+	}
+	for i := range mts {
+		advertise(&mts[i], naming)
+	}
+	return uniqueMetrics(mts), nil
 }
 
-//GetConfigPolicy return configuration policy
+// GetConfigPolicy return configuration policy
 func (iostat *Iostat) GetConfigPolicy() (plugin.ConfigPolicy, error) {
-	c := plugin.NewConfigPolicy()
+	c, err := configPolicy(iostat.prefix)
+	if err != nil {
+		return plugin.ConfigPolicy{}, err
+	}
 	return *c, nil
-
 }
 
 // Init initializes iostat plugin
-func (iostat *Iostat) run(mts []plugin.Metric) ([]string, map[string]float64, error) {
+func (iostat *Iostat) run(fs *hostfs.FS, mts []plugin.Metric) ([]string, map[string]interface{}, []string, error) {
+	cfg := configOf(mts)
+	naming, err := getNaming(cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var namespaces []string
+	var data map[string]interface{}
+	switch mode := getString(cfg, cfgMode, modeIostat); mode {
+	case modeIostat:
+		namespaces, data, err = iostat.runIostat(cfg, naming)
+	case modeInterval:
+		data, err = iostat.collectInterval(fs, mts, naming)
+	default:
+		err = fmt.Errorf("Invalid mode %q (%s has to be %q or %q)", mode, cfgMode, modeIostat, modeInterval)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// devices added below, e.g. from diskstats, are not part of the report
+	reported := devices(data)
+
+	if isRequested(mts, anomalyMetric) {
+		iostat.addAnomalies(cfg, data, naming)
+	}
+	if isRequested(mts, healthMetric) {
+		if err := iostat.addHealth(fs, cfg, data, naming); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if isRequested(mts, mdraid.MdMetric) {
+		if err := iostat.addArrays(fs, data, naming); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if isRequested(mts, sysfs.ZramMetric) {
+		for _, dev := range devices(data) {
+			if !sysfs.IsZram(dev) {
+				continue
+			}
+			for name, v := range sysfs.Zram(fs, dev) {
+				data[iostat.deviceNamespace(dev, sysfs.ZramMetric, name)] = v
+			}
+		}
+	}
+	if isRequested(mts, sysfs.QueueMetric) {
+		for _, dev := range devices(data) {
+			for name, v := range sysfs.Queue(fs, dev) {
+				data[iostat.deviceNamespace(dev, sysfs.QueueMetric, name)] = v
+			}
+		}
+	}
+	if isRequested(mts, diskstats.CounterMetric) {
+		if err := iostat.addCounters(fs, data); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	unit, err := getUnit(cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	namespaces, data = convertUnits(namespaces, data, unit, naming)
+	return namespaces, data, reported, nil
+}
+
+// runIostat runs iostat command and returns namespaces, named in the naming
+// scheme, and values of reported metrics
+func (iostat *Iostat) runIostat(cfg plugin.Config, naming string) ([]string, map[string]interface{}, error) {
 	// TODO: allow the path and/or name of the command to be overriden through the pluginConfigType
 
-	versionString := iostat.cmd.Exec("iostat", []string{"-V"})
+	samples, interval, err := getSampling(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cmd := iostat.runner(cfg)
+	versionString := cmd.Exec("iostat", []string{"-V"})
 	version, err := iostat.parser.ParseVersion(versionString)
 	if err != nil {
 		return nil, nil, err
 	}
-	if version[0] < 10 || (version[0] == 10 && version[1] < 2) {
+	if older(version, sysstat10) {
 		return nil, nil, fmt.Errorf("This plugin requires iostat in version 10.2.0 or newer (version present={%d.%d.%d})", version[0], version[1], version[2])
 	}
 
-	reader, err := iostat.cmd.Run("iostat", getArgs(mts))
+	window := time.Duration(samples*interval) * time.Second
+	reader, err := cmd.Run("iostat", getArgs(cfg, samples, interval), window+cmdTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	namespaces, reports, err := iostat.parser.Parse(reader, iostat.prefix, naming)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return iostat.parser.Parse(reader)
+	data := map[string]interface{}{}
+	for k, v := range summarize(reports) {
+		data[k] = v
+	}
+	return namespaces, data, nil
 }
 
-// updateArgs will add -y to the args provided to iostat telling iostat to report stats
-// since the machine has booted if the config ReportSinceBoot is present and True.  The
-// config for each metric being requested is the same so we need to check the config for
-// one metric being requested.
-func getArgs(mts []plugin.Metric) []string {
+// hostFS returns host filesystem for the host root given in config, detected if
+// it is not given; iostat itself reads /proc/diskstats and /proc/stat which are
+// not namespaced, so it reports host devices even if the plugin is containerised
+func (iostat *Iostat) hostFS(cfg plugin.Config) *hostfs.FS {
+	root := getString(cfg, cfgHostRoot, "")
+
+	iostat.mutex.Lock()
+	defer iostat.mutex.Unlock()
+	if iostat.roots == nil {
+		iostat.roots = map[string]*hostfs.FS{}
+	}
+	fs, ok := iostat.roots[root]
+	if !ok {
+		fs = hostfs.New(root)
+		iostat.roots[root] = fs
+	}
+	return fs
+}
+
+// runner returns runner of iostat selected in config: replay of recorded output
+// if a directory with it is given, otherwise the command itself; runs are
+// recorded if a capture directory is given
+func (iostat *Iostat) runner(cfg plugin.Config) runsCmd {
+	replay := getString(cfg, cfgReplay, "")
+	capture := getString(cfg, cfgCapture, "")
+	if replay == "" && capture == "" {
+		return iostat.cmd
+	}
+	loop := getBool(cfg, cfgReplayLoop, defaultReplayLoop)
+	size := getInt(cfg, cfgCaptureSize, defaultCaptureSize)
+	key := fmt.Sprintf("%s %t %s %d", replay, loop, capture, size)
+
+	iostat.mutex.Lock()
+	defer iostat.mutex.Unlock()
+	if iostat.runners == nil {
+		iostat.runners = map[string]runsCmd{}
+	}
+	if _, ok := iostat.runners[key]; !ok {
+		cmd := iostat.cmd
+		if replay != "" {
+			cmd = command.NewReplay(replay, loop)
+		}
+		if capture != "" {
+			cmd = command.NewCapture(cmd, capture, size)
+		}
+		iostat.runners[key] = cmd
+	}
+	return iostat.runners[key]
+}
+
+// addCounters adds raw cumulative counters since boot of each device listed in /proc/diskstats
+func (iostat *Iostat) addCounters(fs *hostfs.FS, data map[string]interface{}) error {
+	stats, err := diskstats.Read(fs)
+	if err != nil {
+		return err
+	}
+	for dev, s := range iostat.counters.Update(stats) {
+		for i, name := range diskstats.Counters {
+			if name != "" {
+				data[iostat.deviceNamespace(dev, diskstats.CounterMetric, name)] = s[i]
+			}
+		}
+	}
+	return nil
+}
+
+// addArrays adds status of md arrays listed in /proc/mdstat
+func (iostat *Iostat) addArrays(fs *hostfs.FS, data map[string]interface{}, naming string) error {
+	arrays, err := mdraid.Read(fs)
+	if err != nil {
+		return err
+	}
+	for name, array := range arrays {
+		for metric, v := range array.Metrics() {
+			data[iostat.deviceNamespace(name, mdraid.MdMetric, parser.Rename(metric, naming))] = v
+		}
+	}
+	return nil
+}
+
+// getSampling returns number of samples taken per collection and interval
+// between them in seconds, samples are spread evenly over the sample window
+func getSampling(cfg plugin.Config) (int64, int64, error) {
+	samples := getInt(cfg, cfgSamples, defaultSamples)
+	if samples < 1 {
+		return 0, 0, fmt.Errorf("Invalid number of samples (%s = %d)", cfgSamples, samples)
+	}
+	window := getInt(cfg, cfgSampleWindow, samples)
+	interval := window / samples
+	if interval < 1 {
+		return 0, 0, fmt.Errorf("Sample window has to be at least 1 second per sample (%s = %d, %s = %d)",
+			cfgSampleWindow, window, cfgSamples, samples)
+	}
+	return samples, interval, nil
+}
+
+// getArgs will add -y to the args provided to iostat telling iostat to exclude the report
+// since the machine has booted unless the config ReportSinceBoot is present and True,
+// in that case samples are not taken.
+func getArgs(cfg plugin.Config, samples, interval int64) []string {
 	/////////////////////////////////////////////////////////////////////////////////////////
 	// 	IOstat command with interval 1 and options:
 	// 		-c	 	display the CPU utilization report
@@ -209,27 +499,150 @@ func getArgs(mts []plugin.Metric) []string {
 	////////////////////////////////////////////////////////////////////////////////////////
 	iostatArgs := []string{"-c", "-d", "-p", "-g", "ALL", "-x", "-k", "-t"}
 
-	reportLatest := true
-	if len(mts) > 0 && mts[0].Config != nil {
-		if m, ok := mts[0].Config.GetBool("ReportSinceBoot"); ok != nil {
-			if m {
-				reportLatest = false
-			}
-		}
-	}
-	if reportLatest {
+	if !getBool(cfg, cfgReportSinceBoot, false) {
 		// -y will disregards the summary since boot
-		// the "1" "1" arguments will produce 1 result over a 1 second interval
-		iostatArgs = append(iostatArgs, "-y", "1", "1")
+		// the interval and count arguments will produce "samples" results, one per
+		// "interval" seconds (by default 1 result over a 1 second interval)
+		iostatArgs = append(iostatArgs, "-y", strconv.FormatInt(interval, 10), strconv.FormatInt(samples, 10))
 	}
 	return iostatArgs
 }
 
-// extractFromNamespace extracts element of index i from namespace string
-func extractFromNamespace(namespace string, i int) (string, error) {
-	ns := plugin.NewNamespace(strings.Split(strings.TrimPrefix(namespace, "/"), "/")...)
-	if len(ns) < i+1 {
-		return "", fmt.Errorf("Cannot extract element from namespace, index out of range (i = %d)", i)
+// isRequestedGroup checks whether any of requested metrics belongs to the given group, e.g. filesystem
+func isRequestedGroup(mts []plugin.Metric, group string) bool {
+	for _, mt := range mts {
+		if len(mt.Namespace) > 2 && matchElement(mt.Namespace[2].Value, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRequested checks whether any of requested metrics belongs to the given group of device metrics
+func isRequested(mts []plugin.Metric, group string) bool {
+	for _, mt := range mts {
+		ns := mt.Namespace
+		if len(ns) > 5 && matchElement(ns[2].Value, deviceMetric) && matchElement(ns[4].Value, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// devices returns sorted names of devices reported by iostat, without the ALL group
+func devices(data map[string]interface{}) []string {
+	return sortedDevices(groupDevices(data))
+}
+
+// groupDevices returns namespaces of metrics in data grouped by their device,
+// without the ALL group; data is scanned once, so stages handling each device
+// do not scan the whole data for every device
+func groupDevices(data map[string]interface{}) map[string][]string {
+	groups := map[string][]string{}
+	for k := range data {
+		dev, _, ok := deviceKey(k)
+		if ok && strings.ToLower(dev) != "all" {
+			groups[dev] = append(groups[dev], k)
+		}
+	}
+	return groups
+}
+
+// sortedDevices returns sorted names of devices of groups
+func sortedDevices(groups map[string][]string) []string {
+	devs := make([]string, 0, len(groups))
+	for dev := range groups {
+		devs = append(devs, dev)
+	}
+	sort.Strings(devs)
+	return devs
+}
+
+// deviceTags returns tags of metrics of each device: its rank, if devices are
+// ranked, RAID level and members of md arrays and topology of NVMe devices
+func deviceTags(fs *hostfs.FS, mts []plugin.Metric, data map[string]interface{}, ranks map[string]int) map[string]map[string]string {
+	tags := map[string]map[string]string{}
+	for dev, rank := range ranks {
+		tags[dev] = map[string]string{rankTag: strconv.Itoa(rank)}
+	}
+
+	requested := false
+	for _, mt := range mts {
+		if len(mt.Namespace) > 3 && mt.Namespace[2].Value == deviceMetric {
+			requested = true
+			break
+		}
+	}
+	if !requested {
+		return tags
+	}
+	for _, dev := range devices(data) {
+		if !nvme.IsDevice(dev) {
+			continue
+		}
+		if t, err := nvme.Tags(fs, dev); err == nil {
+			tags[dev] = withTags(tags[dev], t)
+		} else {
+			log.WithFields(log.Fields{
+				"device": dev,
+				"error":  err,
+			}).Debug("failed to read NVMe controllers of device")
+		}
+	}
+	arrays, err := mdraid.Read(fs)
+	if err != nil {
+		log.WithField("error", err).Debug("failed to read md arrays")
+		return tags
+	}
+	for name, array := range arrays {
+		tags[name] = withTags(tags[name], array.Tags())
+	}
+	return tags
+}
+
+// hideNvmePaths removes metrics of paths of NVMe namespaces through their
+// controllers, leaving metrics of the namespaces
+func hideNvmePaths(data map[string]interface{}) {
+	for dev, keys := range groupDevices(data) {
+		if !nvme.IsPath(dev) {
+			continue
+		}
+		removeKeys(data, keys)
+	}
+}
+
+// withTags returns tags extended with extra ones
+func withTags(tags, extra map[string]string) map[string]string {
+	if len(extra) == 0 {
+		return tags
+	}
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	for k, v := range extra {
+		tags[k] = v
+	}
+	return tags
+}
+
+// removeKeys removes metrics of the namespaces, e.g. of a device grouped by groupDevices
+func removeKeys(data map[string]interface{}, keys []string) {
+	for _, k := range keys {
+		delete(data, k)
+	}
+}
+
+// deviceNamespace returns namespace of the device metric as a string
+func (iostat *Iostat) deviceNamespace(dev string, elems ...string) string {
+	return plugin.NewNamespace(iostat.prefix.Vendor, iostat.prefix.Type, deviceMetric, dev).AddStaticElements(elems...).String()
+}
+
+// deviceKey returns the device and name of the metric of a device metric namespace,
+// the prefix is not checked as all metrics in data are under the same one
+func deviceKey(namespace string) (dev, name string, ok bool) {
+	elems := strings.SplitN(namespace, "/", 6)
+	if len(elems) < 6 || elems[0] != "" || elems[3] != deviceMetric {
+		return "", "", false
 	}
-	return ns[i].Value, nil
+	return elems[4], elems[5], true
 }
@@ -20,10 +20,18 @@ limitations under the License.
 package iostat
 
 import (
+	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/diskstats"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/filesystem"
 	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/parser"
 
 	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
@@ -223,12 +231,81 @@ var dynamicMockMts = []plugin.Metric{
 	},
 }
 
-type mockCmdRunner struct{}
+var mockSamplesCmdOut = `Linux 4.4.0-66-generic (node-1) 	03/01/2017 	_x86_64_	(4 CPU)
 
-func (c *mockCmdRunner) Run(cmd string, args []string) (io.Reader, error) {
+03/01/2017 12:00:01 PM
+avg-cpu:  %user   %nice %system %iowait  %steal   %idle
+           1.00    0.00    1.00    2.00    0.00   96.00
+
+Device:         rrqm/s   wrqm/s     r/s     w/s    rkB/s    wkB/s avgrq-sz avgqu-sz   await r_await w_await  svctm  %util
+sda               0.00     1.00    0.00   10.00     0.00    40.00     8.00     0.10    1.00    0.00    1.00   0.50   5.00
+ ALL              0.00     1.00    0.00   10.00     0.00    40.00     8.00     0.10    1.00    0.00    1.00   0.50   5.00
+
+03/01/2017 12:00:02 PM
+avg-cpu:  %user   %nice %system %iowait  %steal   %idle
+           3.00    0.00    1.00    2.00    0.00   94.00
+
+Device:         rrqm/s   wrqm/s     r/s     w/s    rkB/s    wkB/s avgrq-sz avgqu-sz   await r_await w_await  svctm  %util
+sda               0.00     1.00    0.00   10.00     0.00    40.00     8.00     0.10  500.00    0.00  500.00   0.50  50.00
+ ALL              0.00     1.00    0.00   10.00     0.00    40.00     8.00     0.10  500.00    0.00  500.00   0.50  50.00
+
+03/01/2017 12:00:03 PM
+avg-cpu:  %user   %nice %system %iowait  %steal   %idle
+           2.00    0.00    1.00    2.00    0.00   95.00
+
+Device:         rrqm/s   wrqm/s     r/s     w/s    rkB/s    wkB/s avgrq-sz avgqu-sz   await r_await w_await  svctm  %util
+sda               0.00     1.00    0.00   10.00     0.00    40.00     8.00     0.10    3.00    0.00    3.00   0.50   5.00
+ ALL              0.00     1.00    0.00   10.00     0.00    40.00     8.00     0.10    3.00    0.00    3.00   0.50   5.00
+
+`
+
+var mockNvmeCmdOut = `Linux 5.15.0-1019-aws (node-2) 	09/01/2022 	_x86_64_	(2 CPU)
+
+09/01/2022 10:00:01 AM
+avg-cpu:  %user   %nice %system %iowait  %steal   %idle
+           1.00    0.00    1.00    2.00    0.00   96.00
+
+Device:         rrqm/s   wrqm/s     r/s     w/s    rkB/s    wkB/s avgrq-sz avgqu-sz   await r_await w_await  svctm  %util
+nvme0n1           0.00     1.00    0.00   10.00     0.00    40.00     8.00     0.10    1.00    0.00    1.00   0.50   5.00
+nvme1n1           0.00     2.00    0.00   20.00     0.00    80.00     8.00     0.20    1.00    0.00    1.00   0.50   8.00
+nvme1c1n1         0.00     1.00    0.00   10.00     0.00    40.00     8.00     0.10    1.00    0.00    1.00   0.50   4.00
+nvme1c2n1         0.00     1.00    0.00   10.00     0.00    40.00     8.00     0.10    1.00    0.00    1.00   0.50   4.00
+ ALL              0.00     5.00    0.00   50.00     0.00   200.00     8.00     0.10    1.00    0.00    1.00   0.50   5.25
+
+`
+
+var mockZramCmdOut = `Linux 5.15.0-1019-aws (node-2) 	09/01/2022 	_x86_64_	(2 CPU)
+
+09/01/2022 10:00:01 AM
+avg-cpu:  %user   %nice %system %iowait  %steal   %idle
+           1.00    0.00    1.00    2.00    0.00   96.00
+
+Device:         rrqm/s   wrqm/s     r/s     w/s    rkB/s    wkB/s avgrq-sz avgqu-sz   await r_await w_await  svctm  %util
+sda               0.00     1.00    0.00   10.00     0.00    40.00     8.00     0.10    1.00    0.00    1.00   0.50   5.00
+zram0             0.00     0.00   12.00   30.00    48.00   120.00     8.00     0.00    0.00    0.00    0.00   0.00   0.00
+ ALL              0.00     1.00   12.00   40.00    48.00   160.00     8.00     0.05    0.50    0.00    0.50   0.25   2.50
+
+`
+
+type mockCmdRunner struct {
+	out     string
+	version string
+	args    []string
+	timeout time.Duration
+}
+
+func (c *mockCmdRunner) Run(cmd string, args []string, timeout time.Duration) (io.Reader, error) {
+	c.args = args
+	c.timeout = timeout
+	if c.out != "" {
+		return strings.NewReader(c.out), nil
+	}
 	return strings.NewReader(mockCmdOut), nil
 }
 func (c *mockCmdRunner) Exec(cmd string, args []string) string {
+	if c.version != "" {
+		return c.version
+	}
 	return mockExecOut
 }
 
@@ -237,7 +314,7 @@ func (c *mockCmdRunner) Exec(cmd string, args []string) string {
 //////////////////////////////////////////////////////////////////////////////
 
 func TestIostat(t *testing.T) {
-	iostat := &Iostat{parser: parser.New(), cmd: &mockCmdRunner{}}
+	iostat := &Iostat{prefix: parser.DefaultPrefix, parser: parser.New(), cmd: &mockCmdRunner{}, counters: diskstats.NewTracker()}
 
 	Convey("Given invalid metric namespace collect metrics", t, func() {
 		badMetrics := []plugin.Metric{
@@ -285,6 +362,9 @@ func TestIostat(t *testing.T) {
 			So(ok, ShouldBeTrue)
 			So(v, ShouldEqual, val)
 		}
+		// the ALL row of the mock output is localized with decimal commas
+		So(m["/intel/iostat/device/ALL/avgrq-sz"], ShouldEqual, 45.65)
+		So(m["/intel/iostat/device/ALL/await"], ShouldEqual, 1.82)
 
 		for _, r := range result {
 			_, ok := r.Data.(float64)
@@ -295,7 +375,7 @@ func TestIostat(t *testing.T) {
 	Convey("Get metric types", t, func() {
 		mts, err := iostat.GetMetricTypes(plugin.Config{})
 		So(err, ShouldBeNil)
-		So(len(mts), ShouldEqual, 19)
+		So(len(mts), ShouldEqual, 72)
 
 		namespaces := []string{}
 		for _, m := range mts {
@@ -321,11 +401,1392 @@ func TestIostat(t *testing.T) {
 		So(namespaces, ShouldContain, "/intel/iostat/device/*/w_per_sec")
 		So(namespaces, ShouldContain, "/intel/iostat/device/*/wkB_per_sec")
 		So(namespaces, ShouldContain, "/intel/iostat/device/*/wrqm_per_sec")
+		// statistics over samples are not published for a single sample
+		So(namespaces, ShouldNotContain, "/intel/iostat/device/*/await/p95")
+		So(namespaces, ShouldContain, "/intel/iostat/device/*/queue/inflight_reads")
+		So(namespaces, ShouldContain, "/intel/iostat/device/*/queue/scheduler")
+		So(namespaces, ShouldContain, "/intel/iostat/device/*/counter/reads_completed")
+		So(namespaces, ShouldContain, "/intel/iostat/device/*/counter/time_in_queue_ms")
+
+		Convey("with statistics over samples if more samples are taken", func() {
+			mts, err := iostat.GetMetricTypes(plugin.Config{"Samples": int64(3)})
+			So(err, ShouldBeNil)
+			So(len(mts), ShouldEqual, 156)
+
+			namespaces := []string{}
+			for _, m := range mts {
+				namespaces = append(namespaces, m.Namespace.String())
+			}
+			So(namespaces, ShouldContain, "/intel/iostat/device/*/await/min")
+			So(namespaces, ShouldContain, "/intel/iostat/device/*/await/max")
+			So(namespaces, ShouldContain, "/intel/iostat/device/*/await/mean")
+			So(namespaces, ShouldContain, "/intel/iostat/device/*/await/p50")
+			So(namespaces, ShouldContain, "/intel/iostat/device/*/await/p95")
+			So(namespaces, ShouldContain, "/intel/iostat/device/*/%util/p99")
+
+			mts, err = iostat.GetMetricTypes(plugin.Config{"Samples": int64(3), "Mode": "interval"})
+			So(err, ShouldBeNil)
+			for _, m := range mts {
+				So(m.Namespace.String(), ShouldNotEndWith, "/p95")
+			}
+		})
+	})
+
+	Convey("Given counter metrics collect them from /proc/diskstats", t, func() {
+		mts := []plugin.Metric{
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device").
+					AddDynamicElement("device_id", "Device ID").
+					AddStaticElements("counter", "sectors_written"),
+				Config: plugin.Config{"HostRoot": "testdata/host"},
+			},
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "dm-0", "counter", "reads_completed"),
+				Config:    plugin.Config{"HostRoot": "testdata/host"},
+			},
+		}
+		result, err := iostat.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+
+		m := map[string]interface{}{}
+		for _, r := range result {
+			m[r.Namespace.String()] = r.Data
+		}
+		So(m, ShouldResemble, map[string]interface{}{
+			"/intel/iostat/device/sda/counter/sectors_written":   uint64(2658786),
+			"/intel/iostat/device/sda1/counter/sectors_written":  uint64(2658786),
+			"/intel/iostat/device/dm-0/counter/sectors_written":  uint64(2658786),
+			"/intel/iostat/device/loop0/counter/sectors_written": uint64(0),
+			"/intel/iostat/device/dm-0/counter/reads_completed":  uint64(4294967200),
+		})
+	})
+
+	Convey("Given queue metrics collect them from sysfs", t, func() {
+		mts := []plugin.Metric{
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device").
+					AddDynamicElement("device_id", "Device ID").
+					AddStaticElements("queue", "inflight_writes"),
+				Config: plugin.Config{"HostRoot": "testdata/host"},
+			},
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "sda", "queue", "scheduler"),
+				Config:    plugin.Config{"HostRoot": "testdata/host"},
+			},
+		}
+		result, err := iostat.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+
+		m := map[string]interface{}{}
+		for _, r := range result {
+			m[r.Namespace.String()] = r.Data
+		}
+		So(m, ShouldResemble, map[string]interface{}{
+			"/intel/iostat/device/sda/queue/inflight_writes":  uint64(5),
+			"/intel/iostat/device/sda1/queue/inflight_writes": uint64(1),
+			"/intel/iostat/device/sda/queue/scheduler":        "deadline",
+		})
+	})
+
+	Convey("Given health metrics classify devices using thresholds of their kind", t, func() {
+		cfg := plugin.Config{"HostRoot": "testdata/host", "HealthAwaitSSD": 1.5}
+		mts := []plugin.Metric{
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device").
+					AddDynamicElement("device_id", "Device ID").
+					AddStaticElements("health", "saturated"),
+				Config: cfg,
+			},
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "sdb", "health", "latency_class"),
+				Config:    cfg,
+			},
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "sda", "health", "latency_class"),
+				Config:    cfg,
+			},
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "sdb", "health", "saturation_score"),
+				Config:    cfg,
+			},
+		}
+		result, err := iostat.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+
+		m := map[string]interface{}{}
+		for _, r := range result {
+			m[r.Namespace.String()] = r.Data
+		}
+		So(m, ShouldHaveLength, 11)
+		// sdb is not rotational, its await exceeds the configured threshold
+		So(m["/intel/iostat/device/sdb/health/saturated"], ShouldEqual, true)
+		So(m["/intel/iostat/device/sdb/health/latency_class"], ShouldEqual, latencyHigh)
+		So(m["/intel/iostat/device/sdb/health/saturation_score"], ShouldAlmostEqual, 1.83/1.5)
+		// partitions of sdb are unknown to sysfs, so thresholds of rotational disks apply
+		So(m["/intel/iostat/device/sdb1/health/saturated"], ShouldEqual, false)
+		So(m["/intel/iostat/device/sda/health/saturated"], ShouldEqual, false)
+		So(m["/intel/iostat/device/sda/health/latency_class"], ShouldEqual, latencyNormal)
+
+		Convey("rejecting thresholds which are not positive", func() {
+			for _, key := range []string{"HealthUtil", "HealthAwaitHDD", "HealthQueueSSD"} {
+				mts[0].Config = plugin.Config{"HostRoot": "testdata/host", key: int64(0)}
+				_, err := iostat.CollectMetrics(mts[:1])
+				So(err, ShouldNotBeNil)
+			}
+			mts[0].Config = plugin.Config{"HealthQueueGrowth": int64(0)}
+			_, err := iostat.CollectMetrics(mts[:1])
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given queue sizes of devices follow their growth between collections", t, func() {
+		collector := &Iostat{}
+		now := time.Now()
+		growth := collector.queueGrowth("", map[string]float64{"sda": 1, "sdb": 5}, now)
+		So(growth, ShouldResemble, map[string]int64{"sda": 0, "sdb": 0})
+		growth = collector.queueGrowth("", map[string]float64{"sda": 2, "sdb": 4}, now)
+		So(growth, ShouldResemble, map[string]int64{"sda": 1, "sdb": 0})
+		growth = collector.queueGrowth("", map[string]float64{"sda": 3, "sdb": 6}, now)
+		So(growth, ShouldResemble, map[string]int64{"sda": 2, "sdb": 1})
+		// trends of other tasks are kept apart
+		So(collector.queueGrowth("other", map[string]float64{"sda": 9}, now), ShouldResemble, map[string]int64{"sda": 0})
+
+		Convey("forgetting devices which were not seen for long", func() {
+			collector.queueGrowth("", map[string]float64{"sda": 4}, now.Add(queueTrendExpiry+time.Minute))
+			So(collector.queues, ShouldContainKey, " sda")
+			So(collector.queues, ShouldNotContainKey, " sdb")
+			So(collector.queues, ShouldNotContainKey, "other sda")
+		})
+	})
+
+	Convey("Given anomaly metrics score device metrics after the warm-up", t, func() {
+		iostat := NewIostatCollector(parser.DefaultPrefix)
+		iostat.cmd = &mockCmdRunner{}
+		cfg := plugin.Config{"AnomalyWarmup": int64(2)}
+		mts := []plugin.Metric{
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "sdb", "anomaly", "await"),
+				Config:    cfg,
+			},
+		}
+		for i := 0; i < 2; i++ {
+			result, err := iostat.CollectMetrics(mts)
+			So(err, ShouldBeNil)
+			So(result, ShouldBeEmpty)
+		}
+		result, err := iostat.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(result, ShouldHaveLength, 1)
+		// iostat reports the same await each time, so it does not deviate from the baseline
+		So(result[0].Data, ShouldEqual, 0.0)
+	})
+
+	Convey("Given top devices config collect metrics of the busiest devices only", t, func() {
+		cfg := plugin.Config{"TopDevices": int64(2), "TopDevicesBy": "kB/s"}
+		mts := []plugin.Metric{
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device").
+					AddDynamicElement("device_id", "Device ID").
+					AddStaticElement("wkB_per_sec"),
+				Config: cfg,
+			},
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "sdb1", "await"),
+				Config:    cfg,
+			},
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "sda", "await"),
+				Config:    cfg,
+			},
+		}
+		result, err := iostat.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+
+		tags := map[string]map[string]string{}
+		for _, r := range result {
+			tags[r.Namespace.String()] = r.Tags
+		}
+		So(tags, ShouldResemble, map[string]map[string]string{
+			"/intel/iostat/device/sdb/wkB_per_sec":  map[string]string{"dev": "sdb", "rank": "1"},
+			"/intel/iostat/device/sdb1/wkB_per_sec": map[string]string{"dev": "sdb1", "rank": "2"},
+			"/intel/iostat/device/ALL/wkB_per_sec":  map[string]string{"dev": "ALL"},
+			"/intel/iostat/device/sdb1/await":       map[string]string{"rank": "2"},
+		})
+
+		Convey("ranking devices reported by iostat only", func() {
+			cfg := plugin.Config{"HostRoot": "testdata/host", "TopDevices": int64(10)}
+			result, err := iostat.CollectMetrics([]plugin.Metric{
+				plugin.Metric{
+					Namespace: plugin.NewNamespace("intel", "iostat", "device").
+						AddDynamicElement("device_id", "Device ID").
+						AddStaticElements("counter", "sectors_written"),
+					Config: cfg,
+				},
+			})
+			So(err, ShouldBeNil)
+
+			tags := map[string]map[string]string{}
+			for _, r := range result {
+				tags[r.Namespace.String()] = r.Tags
+			}
+			// loop0 and dm-0 are known from diskstats only
+			So(tags, ShouldResemble, map[string]map[string]string{
+				"/intel/iostat/device/sda/counter/sectors_written":  map[string]string{"dev": "sda", "rank": "1"},
+				"/intel/iostat/device/sda1/counter/sectors_written": map[string]string{"dev": "sda1", "rank": "2"},
+			})
+		})
+
+		Convey("collecting filesystems of the ranked devices only", func() {
+			cfg := plugin.Config{"HostRoot": "testdata/host", "TopDevices": int64(2), "TopDevicesBy": "kB/s", "FilesystemTypes": "nfs4"}
+			result, err := iostat.CollectMetrics([]plugin.Metric{
+				plugin.Metric{
+					Namespace: plugin.NewNamespace("intel", "iostat", "filesystem").
+						AddDynamicElement("mount", "Mount point").
+						AddStaticElement("bytes_total"),
+					Config: cfg,
+				},
+			})
+			So(err, ShouldBeNil)
+			So(result, ShouldHaveLength, 1)
+			So(result[0].Namespace.String(), ShouldEqual, `/intel/iostat/filesystem/mnt-backup\x20disk/bytes_total`)
+		})
+
+		Convey("ranking by throughput in the configured unit only", func() {
+			values := map[string]float64{"rkB_per_sec": 1, "wkB_per_sec": 2, "rbytes_per_sec": 1024, "wbytes_per_sec": 2048}
+			So(rankings["kB/s"](values, "kB"), ShouldEqual, 3)
+			So(rankings["kB/s"](values, "bytes"), ShouldEqual, 3072)
+		})
+
+		Convey("returning an error for unknown ranking metric", func() {
+			mts[0].Config = plugin.Config{"TopDevices": int64(2), "TopDevicesBy": "svctm"}
+			_, err := iostat.CollectMetrics(mts[:1])
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given device metrics classify its latency", t, func() {
+		t := thresholds{util: 90, await: 50, queue: 4}
+		So(health(t, map[string]float64{"await": 10})["latency_class"], ShouldEqual, latencyNormal)
+		So(health(t, map[string]float64{"await": 30})["latency_class"], ShouldEqual, latencyElevated)
+		So(health(t, map[string]float64{"%util": 99.5})["saturated"], ShouldEqual, true)
+		So(health(t, map[string]float64{"avgqu-sz": 2})["saturation_score"], ShouldEqual, 0.5)
+
+		Convey("computing await from reads and writes when it is not reported", func() {
+			h := health(t, map[string]float64{"r_await": 10, "r_per_sec": 1, "w_await": 110, "w_per_sec": 1, "aqu-sz": 8})
+			So(h["latency_class"], ShouldEqual, latencyHigh)
+			So(h["saturation_score"], ShouldEqual, 2)
+		})
+	})
+
+	Convey("Given md metrics collect status of md arrays", t, func() {
+		cfg := plugin.Config{"HostRoot": "testdata/host"}
+		mts := []plugin.Metric{
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device").
+					AddDynamicElement("device_id", "Device ID").
+					AddStaticElements("md", "degraded"),
+				Config: cfg,
+			},
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "md127", "md", "sync_action"),
+				Config:    cfg,
+			},
+		}
+		result, err := iostat.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+
+		m := map[string]interface{}{}
+		tags := map[string]map[string]string{}
+		for _, r := range result {
+			m[r.Namespace.String()] = r.Data
+			tags[r.Namespace.String()] = r.Tags
+		}
+		So(m, ShouldResemble, map[string]interface{}{
+			"/intel/iostat/device/md127/md/degraded":    uint64(1),
+			"/intel/iostat/device/md0/md/degraded":      uint64(1),
+			"/intel/iostat/device/md1/md/degraded":      uint64(0),
+			"/intel/iostat/device/md127/md/sync_action": "recover",
+		})
+
+		Convey("tagging metrics of arrays with RAID level and members", func() {
+			So(tags["/intel/iostat/device/md127/md/sync_action"], ShouldResemble, map[string]string{
+				"raid_level":   "raid5",
+				"raid_members": "sdb1,sdc1,sdd1",
+			})
+			So(tags["/intel/iostat/device/md0/md/degraded"], ShouldResemble, map[string]string{
+				"dev":          "md0",
+				"raid_level":   "raid1",
+				"raid_members": "sda2,sdb2",
+			})
+		})
+
+		Convey("converting synchronization speed into the configured unit", func() {
+			for _, c := range []struct {
+				cfg  plugin.Config
+				name string
+				data interface{}
+			}{
+				{plugin.Config{"HostRoot": "testdata/host"}, "sync_speed_kB_per_sec", uint64(123456)},
+				{plugin.Config{"HostRoot": "testdata/host", "Units": "bytes"}, "sync_speed_bytes_per_sec", uint64(123456 * 1024)},
+				{plugin.Config{"HostRoot": "testdata/host", "Units": "MB"}, "sync_speed_MB_per_sec", 123456.0 / 1024},
+				{plugin.Config{"HostRoot": "testdata/host", "Units": "MB", "Naming": "snake_case"}, "sync_speed_mb_per_sec", 123456.0 / 1024},
+			} {
+				result, err := iostat.CollectMetrics([]plugin.Metric{
+					plugin.Metric{
+						Namespace: plugin.NewNamespace("intel", "iostat", "device", "md127", "md", c.name),
+						Config:    c.cfg,
+					},
+				})
+				So(err, ShouldBeNil)
+				So(result, ShouldHaveLength, 1)
+				So(result[0].Data, ShouldEqual, c.data)
+				So(result[0].Description, ShouldNotBeEmpty)
+			}
+		})
+	})
+
+	Convey("Given host without md driver collect metrics of all groups", t, func() {
+		root, err := ioutil.TempDir("", "host")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(root)
+		So(os.Mkdir(filepath.Join(root, "proc"), 0755), ShouldBeNil)
+		for _, file := range []string{"diskstats", "stat"} {
+			content, err := ioutil.ReadFile(filepath.Join("testdata", "host", "proc", file))
+			So(err, ShouldBeNil)
+			So(ioutil.WriteFile(filepath.Join(root, "proc", file), content, 0644), ShouldBeNil)
+		}
+
+		collector := NewIostatCollector(parser.DefaultPrefix)
+		collector.cmd = &mockCmdRunner{}
+		result, err := collector.CollectMetrics([]plugin.Metric{
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "sda", "*", "*"),
+				Config:    plugin.Config{"HostRoot": root},
+			},
+		})
+		So(err, ShouldBeNil)
+		So(result, ShouldNotBeEmpty)
+		for _, r := range result {
+			So(r.Namespace[4].Value, ShouldNotEqual, "md")
+		}
+	})
+
+	Convey("Given NVMe devices tag their metrics with topology of controllers", t, func() {
+		iostat := &Iostat{prefix: parser.DefaultPrefix, parser: parser.New(), cmd: &mockCmdRunner{out: mockNvmeCmdOut}}
+		cfg := plugin.Config{"HostRoot": "testdata/host"}
+		mts := []plugin.Metric{
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device").
+					AddDynamicElement("device_id", "Device ID").
+					AddStaticElement("w_per_sec"),
+				Config: cfg,
+			},
+		}
+		result, err := iostat.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+
+		tags := map[string]map[string]string{}
+		for _, r := range result {
+			tags[r.Tags["dev"]] = r.Tags
+		}
+		So(tags, ShouldHaveLength, 5)
+		So(tags["nvme0n1"]["volume_id"], ShouldEqual, "vol-0123456789abcdef0")
+		So(tags["nvme1n1"]["nvme_controller"], ShouldEqual, "nvme1,nvme2")
+		So(tags["nvme1c1n1"]["nvme_transport"], ShouldEqual, "tcp")
+		So(tags["ALL"], ShouldResemble, map[string]string{"dev": "ALL"})
+
+		Convey("hiding paths of namespaces if configured", func() {
+			mts[0].Config = plugin.Config{"HostRoot": "testdata/host", "HideNvmePaths": true}
+			result, err := iostat.CollectMetrics(mts)
+			So(err, ShouldBeNil)
+
+			devs := []string{}
+			for _, r := range result {
+				devs = append(devs, r.Tags["dev"])
+			}
+			So(devs, ShouldHaveLength, 3)
+			So(devs, ShouldNotContain, "nvme1c1n1")
+			So(devs, ShouldNotContain, "nvme1c2n1")
+		})
+	})
+
+	Convey("Given zram metrics collect them for zram devices", t, func() {
+		iostat := &Iostat{prefix: parser.DefaultPrefix, parser: parser.New(), cmd: &mockCmdRunner{out: mockZramCmdOut}}
+		cfg := plugin.Config{"HostRoot": "testdata/host"}
+		mts := []plugin.Metric{
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device").
+					AddDynamicElement("device_id", "Device ID").
+					AddStaticElements("zram", "compression_ratio"),
+				Config: cfg,
+			},
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "zram0", "zram", "failed_writes"),
+				Config:    cfg,
+			},
+		}
+		result, err := iostat.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+
+		m := map[string]interface{}{}
+		for _, r := range result {
+			m[r.Namespace.String()] = r.Data
+		}
+		So(m, ShouldResemble, map[string]interface{}{
+			"/intel/iostat/device/zram0/zram/compression_ratio": 4.0,
+			"/intel/iostat/device/zram0/zram/failed_writes":     uint64(2),
+		})
+	})
+
+	Convey("Given filesystem metrics collect them for mounts of reported devices", t, func() {
+		cfg := plugin.Config{"HostRoot": "testdata/host"}
+		mts := []plugin.Metric{
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "filesystem").
+					AddDynamicElement("mount", "Mount point").
+					AddStaticElement("bytes_total"),
+				Config: cfg,
+			},
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "filesystem", "-", "inodes_used"),
+				Config:    cfg,
+			},
+		}
+		result, err := iostat.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+
+		tags := map[string]map[string]string{}
+		for _, r := range result {
+			tags[r.Namespace.String()] = r.Tags
+		}
+		So(tags, ShouldResemble, map[string]map[string]string{
+			"/intel/iostat/filesystem/-/bytes_total":                  map[string]string{"device": "sda1", "fstype": "ext4", "mount_point": "/"},
+			`/intel/iostat/filesystem/mnt-backup\x20disk/bytes_total`: map[string]string{"device": "sdb1", "fstype": "ext4", "mount_point": "/mnt/backup disk"},
+			"/intel/iostat/filesystem/-/inodes_used":                  map[string]string{"device": "sda1", "fstype": "ext4", "mount_point": "/"},
+		})
+
+		Convey("skipping filesystems of devices known from diskstats only", func() {
+			// /data is mounted from dm-0, which is not reported by iostat
+			mts[1] = plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device").
+					AddDynamicElement("device_id", "Device ID").
+					AddStaticElements("counter", "sectors_written"),
+				Config: cfg,
+			}
+			result, err := iostat.CollectMetrics(mts)
+			So(err, ShouldBeNil)
+
+			mounts := map[string]bool{}
+			for _, r := range result {
+				if r.Namespace[2].Value == filesystem.FilesystemMetric {
+					mounts[r.Namespace[3].Value] = true
+				}
+			}
+			So(mounts, ShouldResemble, map[string]bool{"-": true, `mnt-backup\x20disk`: true})
+		})
+
+		Convey("including filesystems of configured types", func() {
+			mts[0].Config = plugin.Config{"HostRoot": "testdata/host", "FilesystemTypes": "sysfs, nfs4"}
+			result, err := iostat.CollectMetrics(mts[:1])
+			So(err, ShouldBeNil)
+
+			tags := map[string]map[string]string{}
+			for _, r := range result {
+				tags[r.Namespace.String()] = r.Tags
+			}
+			So(tags, ShouldHaveLength, 3)
+			So(tags["/intel/iostat/filesystem/sys/bytes_total"], ShouldResemble, map[string]string{"device": "sysfs", "fstype": "sysfs", "mount_point": "/sys"})
+		})
+	})
+
+	Convey("Given throughput unit convert throughput metrics into it", t, func() {
+		cfg := plugin.Config{"Units": "bytes"}
+		mts := []plugin.Metric{
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "sdb", "wbytes_per_sec"),
+				Config:    cfg,
+			},
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "sdb", "avgrq_bytes"),
+				Config:    cfg,
+			},
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "sdb", "avgrq-sz"),
+				Config:    cfg,
+			},
+		}
+		result, err := iostat.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+
+		m := map[string]interface{}{}
+		for _, r := range result {
+			m[r.Namespace.String()] = r.Data
+		}
+		So(m, ShouldHaveLength, 3)
+		So(m["/intel/iostat/device/sdb/wbytes_per_sec"], ShouldAlmostEqual, 15.34*1024)
+		So(m["/intel/iostat/device/sdb/avgrq_bytes"], ShouldAlmostEqual, 45.70*512)
+		So(m["/intel/iostat/device/sdb/avgrq-sz"], ShouldEqual, 45.70)
+
+		Convey("naming metric types after the unit", func() {
+			mts, err := iostat.GetMetricTypes(plugin.Config{"Units": "MB", "Samples": int64(2)})
+			So(err, ShouldBeNil)
+			names := []string{}
+			for _, mt := range mts {
+				names = append(names, mt.Namespace.String())
+			}
+			So(names, ShouldContain, "/intel/iostat/device/*/rMB_per_sec")
+			So(names, ShouldContain, "/intel/iostat/device/*/rMB_per_sec/p95")
+			So(names, ShouldContain, "/intel/iostat/device/*/anomaly/wMB_per_sec")
+			So(names, ShouldContain, "/intel/iostat/device/*/md/sync_speed_MB_per_sec")
+			So(names, ShouldNotContain, "/intel/iostat/device/*/rkB_per_sec")
+			So(names, ShouldNotContain, "/intel/iostat/device/*/md/sync_speed_kB_per_sec")
+		})
+
+		Convey("returning an error for unknown unit", func() {
+			mts[0].Config = plugin.Config{"Units": "GB"}
+			_, err := iostat.CollectMetrics(mts[:1])
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given snake_case naming scheme name metrics with it", t, func() {
+		cfg := plugin.Config{"Naming": "snake_case"}
+		mts := []plugin.Metric{
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "avg_cpu", "idle_percent"),
+				Config:    cfg,
+			},
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "sdb", "(util_percent|avg_request_size_sectors)"),
+				Config:    cfg,
+			},
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "sdb", "read_kb_per_sec"),
+				Config:    cfg,
+			},
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "sdb", "avg_queue_length"),
+				Config:    cfg,
+			},
+		}
+		result, err := iostat.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		values := map[string]interface{}{}
+		for _, r := range result {
+			values[r.Namespace.String()] = r.Data
+			So(r.Description, ShouldNotBeEmpty)
+		}
+		So(values, ShouldResemble, map[string]interface{}{
+			"/intel/iostat/avg_cpu/idle_percent":                99.37,
+			"/intel/iostat/device/sdb/util_percent":             0.0,
+			"/intel/iostat/device/sdb/avg_request_size_sectors": 45.70,
+			"/intel/iostat/device/sdb/read_kb_per_sec":          2.08,
+			"/intel/iostat/device/sdb/avg_queue_length":         0.0,
+		})
+		// requested metrics are not modified
+		So(mts[0].Namespace.String(), ShouldEqual, "/intel/iostat/avg_cpu/idle_percent")
+
+		Convey("advertising only names of letters, digits and underscores", func() {
+			for _, unit := range []string{"bytes", "kB", "MB"} {
+				all := &Iostat{prefix: parser.DefaultPrefix, parser: parser.New(), cmd: &mockCmdRunner{version: "unknown"}}
+				types, err := all.GetMetricTypes(plugin.Config{"Naming": "snake_case", "Units": unit, "Samples": int64(2)})
+				So(err, ShouldBeNil)
+				names := map[string]bool{}
+				for _, mt := range types {
+					So(mt.Namespace.String(), ShouldNotContainSubstring, "%")
+					So(mt.Namespace.String(), ShouldNotContainSubstring, "-")
+					So(strings.ToLower(mt.Namespace.String()), ShouldEqual, mt.Namespace.String())
+					So(names, ShouldNotContainKey, mt.Namespace.String())
+					names[mt.Namespace.String()] = true
+				}
+				So(names, ShouldContainKey, "/intel/iostat/device/*/util_percent/p95")
+				// avgqu-sz and aqu-sz of any version of sysstat are advertised once
+				So(names, ShouldContainKey, "/intel/iostat/device/*/avg_queue_length")
+			}
+		})
+
+		Convey("keeping legacy names by default", func() {
+			types, err := iostat.GetMetricTypes(plugin.Config{})
+			So(err, ShouldBeNil)
+			names := []string{}
+			for _, mt := range types {
+				names = append(names, mt.Namespace.String())
+			}
+			So(names, ShouldContain, "/intel/iostat/device/*/%util")
+			So(names, ShouldContain, "/intel/iostat/avg-cpu/%iowait")
+		})
+
+		Convey("returning an error for unknown naming scheme", func() {
+			mts[0].Config = plugin.Config{"Naming": "camelCase"}
+			_, err := iostat.CollectMetrics(mts[:1])
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given namespace prefix publish metrics under it", t, func() {
+		prefix, err := parser.NewPrefix("acme", "diskio")
+		So(err, ShouldBeNil)
+		cfg := plugin.Config{"HostRoot": "testdata/host"}
+		collect := func(out string, namespaces ...plugin.Namespace) map[string]plugin.Metric {
+			collector := NewIostatCollector(prefix)
+			collector.cmd = &mockCmdRunner{out: out}
+			mts := []plugin.Metric{}
+			for _, ns := range namespaces {
+				mts = append(mts, plugin.Metric{Namespace: ns, Config: cfg})
+			}
+			result, err := collector.CollectMetrics(mts)
+			So(err, ShouldBeNil)
+			m := map[string]plugin.Metric{}
+			for _, r := range result {
+				So(r.Namespace.Strings()[:2], ShouldResemble, []string{"acme", "diskio"})
+				m[r.Namespace.String()] = r
+			}
+			return m
+		}
+
+		types, err := NewIostatCollector(prefix).GetMetricTypes(cfg)
+		So(err, ShouldBeNil)
+		So(types, ShouldNotBeEmpty)
+		for _, mt := range types {
+			So(mt.Namespace.Strings()[:2], ShouldResemble, []string{"acme", "diskio"})
+			So(mt.Description, ShouldNotBeEmpty)
+		}
+		policy, err := NewIostatCollector(prefix).GetConfigPolicy()
+		So(err, ShouldBeNil)
+		expected, err := configPolicy(prefix)
+		So(err, ShouldBeNil)
+		So(&policy, ShouldResemble, expected)
+
+		m := collect("",
+			plugin.NewNamespace("acme", "diskio", "avg-cpu", "%idle"),
+			plugin.NewNamespace("acme", "diskio", "device", "*", "avgrq_bytes"),
+			plugin.NewNamespace("acme", "diskio", "filesystem", "*", "bytes_total"),
+			plugin.NewNamespace("acme", "diskio", "device", "*", "md", "degraded"),
+		)
+		So(m, ShouldContainKey, "/acme/diskio/avg-cpu/%idle")
+		So(m["/acme/diskio/avg-cpu/%idle"].Description, ShouldNotBeEmpty)
+		So(m, ShouldContainKey, "/acme/diskio/device/sda/avgrq_bytes")
+		So(m, ShouldContainKey, "/acme/diskio/filesystem/-/bytes_total")
+		So(m["/acme/diskio/filesystem/-/bytes_total"].Tags["device"], ShouldEqual, "sda1")
+		So(m, ShouldContainKey, "/acme/diskio/device/md127/md/degraded")
+
+		m = collect(mockZramCmdOut, plugin.NewNamespace("acme", "diskio", "device", "*", "zram", "compression_ratio"))
+		So(m, ShouldContainKey, "/acme/diskio/device/zram0/zram/compression_ratio")
+		So(m["/acme/diskio/device/zram0/zram/compression_ratio"].Data, ShouldEqual, 4.0)
+
+		Convey("leaving collectors under the default prefix unchanged", func() {
+			types, err := NewIostatCollector(parser.DefaultPrefix).GetMetricTypes(cfg)
+			So(err, ShouldBeNil)
+			for _, mt := range types {
+				So(mt.Namespace.Strings()[:2], ShouldResemble, []string{"intel", "iostat"})
+			}
+		})
+	})
+
+	Convey("Given sysstat data file backfill recorded metrics", t, func() {
+		out, err := ioutil.ReadFile("testdata/sadf/sysstat11.json")
+		So(err, ShouldBeNil)
+		cmd := &mockCmdRunner{out: string(out)}
+		sa := &Iostat{prefix: parser.DefaultPrefix, parser: parser.New(), cmd: cmd}
+		backfill := func(cfg plugin.Config, namespaces ...plugin.Namespace) []plugin.Metric {
+			mts := []plugin.Metric{}
+			for _, ns := range namespaces {
+				mts = append(mts, plugin.Metric{Namespace: ns, Config: cfg})
+			}
+			result, err := sa.Backfill(mts, "/var/log/sa/sa01", "10:00:00", "")
+			So(err, ShouldBeNil)
+			return result
+		}
+
+		result := backfill(nil,
+			plugin.NewNamespace("intel", "iostat", "avg-cpu", "%idle"),
+			plugin.NewNamespace("intel", "iostat", "device").
+				AddDynamicElement("device_id", "Device ID").
+				AddStaticElement("wkB_per_sec"),
+			plugin.NewNamespace("intel", "iostat", "device", "sda", "avgrq_bytes"),
+			plugin.NewNamespace("intel", "iostat", "device", "*", "queue", "scheduler"),
+		)
+		So(cmd.args, ShouldResemble, []string{"-j", "-s", "10:00:00", "/var/log/sa/sa01", "--", "-u", "-d", "-p"})
+		// two records of CPU, two devices and request size of sda
+		So(result, ShouldHaveLength, 2*(1+2+1))
+		So(result[0].Namespace.String(), ShouldEqual, "/intel/iostat/avg-cpu/%idle")
+		So(result[0].Data, ShouldEqual, 98.15)
+		So(result[0].Timestamp, ShouldResemble, time.Date(2017, 3, 1, 10, 10, 1, 0, time.UTC))
+		So(result[0].Description, ShouldNotBeEmpty)
+		So(result[1].Namespace.String(), ShouldEqual, "/intel/iostat/device/sda/wkB_per_sec")
+		So(result[1].Data, ShouldEqual, 1234.56)
+		So(result[1].Tags, ShouldResemble, map[string]string{"dev": "sda"})
+		So(result[3].Namespace.String(), ShouldEqual, "/intel/iostat/device/sda/avgrq_bytes")
+		So(result[3].Data, ShouldAlmostEqual, 259.71*512)
+		So(result[7].Timestamp, ShouldResemble, time.Date(2017, 3, 1, 10, 20, 1, 0, time.UTC))
+
+		Convey("named and converted as collected metrics", func() {
+			result := backfill(plugin.Config{"Units": "MB", "Naming": "snake_case"},
+				plugin.NewNamespace("intel", "iostat", "device", "sda", "write_mb_per_sec"))
+			So(result, ShouldHaveLength, 2)
+			So(result[0].Namespace.String(), ShouldEqual, "/intel/iostat/device/sda/write_mb_per_sec")
+			So(result[0].Data, ShouldAlmostEqual, 1234.56/1024)
+		})
+
+		Convey("returning an error if sadf output is invalid", func() {
+			cmd.out = "Invalid system activity file: /var/log/sa/sa01"
+			_, err := sa.Backfill([]plugin.Metric{
+				plugin.Metric{Namespace: plugin.NewNamespace("intel", "iostat", "avg-cpu", "%idle")},
+			}, "/var/log/sa/sa01", "", "")
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given recorded output of iostat replay it instead of running iostat", t, func() {
+		cmd := &mockCmdRunner{}
+		replayed := &Iostat{prefix: parser.DefaultPrefix, parser: parser.New(), cmd: cmd}
+		idle := func(cfg plugin.Config) (interface{}, error) {
+			result, err := replayed.CollectMetrics([]plugin.Metric{
+				plugin.Metric{Namespace: plugin.NewNamespace("intel", "iostat", "avg-cpu", "%idle"), Config: cfg},
+			})
+			if err != nil || len(result) == 0 {
+				return nil, err
+			}
+			return result[0].Data, nil
+		}
+
+		cfg := plugin.Config{"Replay": "testdata/replay"}
+		for _, expected := range []float64{98.15, 90, 98.15} {
+			v, err := idle(cfg)
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, expected)
+		}
+		So(cmd.args, ShouldBeNil)
+
+		Convey("stopping at the end unless looping", func() {
+			cfg := plugin.Config{"Replay": "testdata/replay", "ReplayLoop": false}
+			for i := 0; i < 2; i++ {
+				_, err := idle(cfg)
+				So(err, ShouldBeNil)
+			}
+			_, err := idle(cfg)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("advertising metrics of the recorded version", func() {
+			mts, err := replayed.GetMetricTypes(cfg)
+			So(err, ShouldBeNil)
+			names := []string{}
+			for _, mt := range mts {
+				names = append(names, mt.Namespace.String())
+			}
+			So(names, ShouldContain, "/intel/iostat/device/*/avgqu-sz")
+			So(names, ShouldNotContain, "/intel/iostat/device/*/aqu-sz")
+		})
+	})
+
+	Convey("Given capture directory record runs of iostat to be replayed", t, func() {
+		dir, err := ioutil.TempDir("", "capture")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		request := func(iostat *Iostat, cfg plugin.Config) []plugin.Metric {
+			result, err := iostat.CollectMetrics([]plugin.Metric{
+				plugin.Metric{Namespace: plugin.NewNamespace("intel", "iostat", "device", "sdb", "await"), Config: cfg},
+			})
+			So(err, ShouldBeNil)
+			return result
+		}
+		captured := request(&Iostat{prefix: parser.DefaultPrefix, parser: parser.New(), cmd: &mockCmdRunner{}}, plugin.Config{"Capture": dir})
+		So(captured, ShouldHaveLength, 1)
+
+		args, err := filepath.Glob(filepath.Join(dir, "*.args"))
+		So(err, ShouldBeNil)
+		So(args, ShouldHaveLength, 1)
+		content, err := ioutil.ReadFile(args[0])
+		So(err, ShouldBeNil)
+		So(string(content), ShouldStartWith, "iostat -c -d -p -g ALL -x -k -t")
+
+		// replay does not need the command
+		replayed := request(&Iostat{prefix: parser.DefaultPrefix, parser: parser.New()}, plugin.Config{"Replay": dir})
+		So(replayed, ShouldHaveLength, 1)
+		So(replayed[0].Data, ShouldEqual, captured[0].Data)
+	})
+
+	Convey("Given version of iostat get metric types without running it", t, func() {
+		cmd := &mockCmdRunner{version: "sysstat version 12.5.4\n(C) Sebastien Godard (sysstat <at> orange.fr)"}
+		iostat := &Iostat{prefix: parser.DefaultPrefix, parser: parser.New(), cmd: cmd}
+		mts, err := iostat.GetMetricTypes(plugin.Config{"Samples": int64(2)})
+		So(err, ShouldBeNil)
+		So(cmd.args, ShouldBeNil)
+
+		namespaces := map[string]map[string]string{}
+		for _, m := range mts {
+			namespaces[m.Namespace.String()] = m.Tags
+		}
+		So(namespaces, ShouldContainKey, "/intel/iostat/device/*/aqu-sz")
+		So(namespaces, ShouldContainKey, "/intel/iostat/device/*/rareq_bytes")
+		So(namespaces, ShouldContainKey, "/intel/iostat/device/*/f_await/p95")
+		So(namespaces, ShouldNotContainKey, "/intel/iostat/device/*/await")
+		So(namespaces, ShouldNotContainKey, "/intel/iostat/device/*/svctm")
+
+		Convey("advertising metrics of all versions if iostat is missing", func() {
+			cmd.version = "sh: iostat: command not found"
+			mts, err := iostat.GetMetricTypes(plugin.Config{})
+			So(err, ShouldBeNil)
+			namespaces := []string{}
+			for _, m := range mts {
+				namespaces = append(namespaces, m.Namespace.String())
+			}
+			So(namespaces, ShouldContain, "/intel/iostat/device/*/await")
+			So(namespaces, ShouldContain, "/intel/iostat/device/*/aqu-sz")
+		})
+
+		Convey("marking metrics not reported on this host if verification is enabled", func() {
+			mts, err := iostat.GetMetricTypes(plugin.Config{"VerifyMetrics": true, "Samples": int64(2)})
+			So(err, ShouldBeNil)
+			So(cmd.args, ShouldNotBeNil)
+
+			tags := map[string]map[string]string{}
+			for _, m := range mts {
+				tags[m.Namespace.String()] = m.Tags
+			}
+			// iostat output in old format does not report sysstat 12 metrics
+			So(tags["/intel/iostat/device/*/aqu-sz"], ShouldResemble, map[string]string{"available": "false", "data_type": "float64"})
+			So(tags["/intel/iostat/device/*/aqu-sz/p50"], ShouldResemble, map[string]string{"available": "false", "data_type": "float64"})
+			So(tags["/intel/iostat/device/*/r_await"], ShouldNotContainKey, "available")
+			So(tags["/intel/iostat/avg-cpu/%idle"], ShouldNotContainKey, "available")
+			So(tags["/intel/iostat/device/*/queue/scheduler"], ShouldNotContainKey, "available")
+		})
+	})
+
+	Convey("Given interval mode get metric types computed from /proc/diskstats", t, func() {
+		mts, err := iostat.GetMetricTypes(plugin.Config{"Mode": "interval"})
+		So(err, ShouldBeNil)
+		namespaces := []string{}
+		for _, m := range mts {
+			namespaces = append(namespaces, m.Namespace.String())
+		}
+		So(namespaces, ShouldContain, "/intel/iostat/device/*/await")
+		So(namespaces, ShouldContain, "/intel/iostat/device/*/avgrq_bytes")
+		So(namespaces, ShouldNotContain, "/intel/iostat/device/*/aqu-sz")
+	})
+
+	Convey("Given sysstat versions list device metrics reported by them", t, func() {
+		So(deviceColumnsOf([]int64{11, 2, 0}), ShouldResemble, []string{
+			"rrqm_per_sec", "wrqm_per_sec", "r_per_sec", "w_per_sec", "rkB_per_sec", "wkB_per_sec",
+			"avgrq-sz", "avgqu-sz", "await", "r_await", "w_await", "svctm", "%util",
+		})
+		So(deviceColumnsOf([]int64{12, 0, 3}), ShouldContain, "svctm")
+		So(deviceColumnsOf([]int64{12, 0, 3}), ShouldNotContain, "d_await")
+		So(deviceColumnsOf([]int64{12, 1, 2}), ShouldContain, "d_await")
+		So(deviceColumnsOf([]int64{12, 1, 2}), ShouldNotContain, "svctm")
+		So(deviceColumnsOf(nil), ShouldHaveLength, len(deviceColumns))
+	})
+
+	Convey("Given wildcards and tuples in any position of namespace collect matching metrics", t, func() {
+		collect := func(elems ...string) map[string]map[string]string {
+			result, err := iostat.CollectMetrics([]plugin.Metric{
+				plugin.Metric{Namespace: plugin.NewNamespace(elems...)},
+			})
+			So(err, ShouldBeNil)
+			m := map[string]map[string]string{}
+			for _, r := range result {
+				m[r.Namespace.String()] = r.Tags
+			}
+			return m
+		}
+
+		Convey("for CPU metrics", func() {
+			m := collect("intel", "iostat", "avg-cpu", "*")
+			So(m, ShouldHaveLength, 6)
+			So(m, ShouldContainKey, "/intel/iostat/avg-cpu/%idle")
+
+			m = collect("intel", "iostat", "avg-cpu", "(%user|%system|%bogus)")
+			So(m, ShouldHaveLength, 2)
+			So(m, ShouldContainKey, "/intel/iostat/avg-cpu/%system")
+		})
+
+		Convey("for metrics of a device", func() {
+			m := collect("intel", "iostat", "device", "sda", "*")
+			So(m, ShouldHaveLength, 14)
+			So(m["/intel/iostat/device/sda/await"], ShouldBeNil)
+			So(m, ShouldContainKey, "/intel/iostat/device/sda/avgrq_bytes")
+		})
+
+		Convey("for a tuple of devices", func() {
+			m := collect("intel", "iostat", "device", "(sda|sdb)", "(await|%util)")
+			So(m, ShouldResemble, map[string]map[string]string{
+				"/intel/iostat/device/sda/await": map[string]string{"dev": "sda"},
+				"/intel/iostat/device/sda/%util": map[string]string{"dev": "sda"},
+				"/intel/iostat/device/sdb/await": map[string]string{"dev": "sdb"},
+				"/intel/iostat/device/sdb/%util": map[string]string{"dev": "sdb"},
+			})
+		})
+
+		Convey("for every group", func() {
+			m := collect("intel", "iostat", "*", "*", "%util")
+			So(m, ShouldHaveLength, 9)
+			So(m, ShouldContainKey, "/intel/iostat/device/ALL/%util")
+		})
+
+		Convey("collecting nothing when nothing matches", func() {
+			So(collect("intel", "iostat", "device", "(sdx|sdy)", "await"), ShouldBeEmpty)
+		})
+	})
+
+	Convey("Given namespace element match values", t, func() {
+		So(matchElement("*", "sda"), ShouldBeTrue)
+		So(matchElement("(sda|sdb)", "sdb"), ShouldBeTrue)
+		So(matchElement("(sda|sdb)", "sdc"), ShouldBeFalse)
+		So(matchElement("sda", "sda"), ShouldBeTrue)
+		So(matchElement("sda", "sda1"), ShouldBeFalse)
+		So(isPattern("(sda)"), ShouldBeTrue)
+		So(isPattern("sda"), ShouldBeFalse)
+	})
+
+	Convey("Given index of collected data", t, func() {
+		idx := newIndex(map[string]interface{}{
+			"/intel/iostat/device/sda/await":     1.0,
+			"/intel/iostat/device/sda/await/p95": 2.0,
+			"/intel/iostat/device/sdb/await":     3.0,
+			"/intel/iostat/avg-cpu/%idle":        4.0,
+		})
+		values := func(ns plugin.Namespace) []string {
+			matched := []string{}
+			for _, m := range idx.match(ns) {
+				matched = append(matched, m.String())
+			}
+			return matched
+		}
+
+		Convey("literal namespaces are looked up directly", func() {
+			So(values(plugin.NewNamespace("intel", "iostat", "device", "sda", "await")), ShouldResemble,
+				[]string{"/intel/iostat/device/sda/await"})
+			So(values(plugin.NewNamespace("intel", "iostat", "device", "sda")), ShouldBeEmpty)
+		})
+
+		Convey("wildcards match namespaces of the same length only, in order", func() {
+			So(values(plugin.NewNamespace("intel", "iostat", "device", "*", "await")), ShouldResemble,
+				[]string{"/intel/iostat/device/sda/await", "/intel/iostat/device/sdb/await"})
+			So(values(plugin.NewNamespace("intel", "iostat", "*", "*", "*", "*")), ShouldResemble,
+				[]string{"/intel/iostat/device/sda/await/p95"})
+		})
+
+		Convey("matched namespaces keep dynamic elements of the requested one", func() {
+			ns := plugin.NewNamespace("intel", "iostat", "device").
+				AddDynamicElement("device_id", "Device ID").
+				AddStaticElement("await")
+			ns[3].Value = "(sdb|sdc)"
+			matched := idx.match(ns)
+			So(matched, ShouldHaveLength, 1)
+			So(matched[0][3].Value, ShouldEqual, "sdb")
+			So(matched[0][3].Name, ShouldEqual, "device_id")
+			So(ns[3].Value, ShouldEqual, "(sdb|sdc)")
+		})
+	})
+
+	Convey("Given metric catalog describe every metric type", t, func() {
+		// metrics of all supported versions are advertised if version of iostat is unknown
+		all := &Iostat{prefix: parser.DefaultPrefix, parser: parser.New(), cmd: &mockCmdRunner{version: "unknown"}}
+		for _, unit := range []string{"bytes", "kB", "MB"} {
+			for _, naming := range parser.Namings {
+				mts, err := all.GetMetricTypes(plugin.Config{"Units": unit, "Naming": naming, "Samples": int64(2)})
+				So(err, ShouldBeNil)
+				for _, mt := range mts {
+					info, ok := lookup(mt.Namespace, naming)
+					So(ok, ShouldBeTrue)
+					So(mt.Description, ShouldNotBeEmpty)
+					So(mt.Tags, ShouldContainKey, "data_type")
+					So(mt.Tags["data_type"], ShouldEqual, info.dataType)
+				}
+			}
+		}
+
+		Convey("stamping the same metadata on collected metrics", func() {
+			cfg := plugin.Config{"HostRoot": "testdata/host"}
+			requested := []plugin.Metric{}
+			for _, ns := range [][]string{{"sda", "await"}, {"sda", "await", "p95"}, {"sda", "queue", "scheduler"}, {"sda", "counter", "sectors_read"}, {"sda", "health", "saturated"}} {
+				requested = append(requested, plugin.Metric{
+					Namespace: plugin.NewNamespace("intel", "iostat", "device").AddStaticElements(ns...),
+					Config:    cfg,
+				})
+			}
+			requested = append(requested, plugin.Metric{Namespace: plugin.NewNamespace("intel", "iostat", "avg-cpu", "%idle"), Config: cfg})
+
+			result, err := iostat.CollectMetrics(requested)
+			So(err, ShouldBeNil)
+			So(result, ShouldHaveLength, 5)
+			for _, r := range result {
+				info, ok := lookup(r.Namespace, parser.NamingLegacy)
+				So(ok, ShouldBeTrue)
+				So(r.Description, ShouldEqual, info.description)
+				So(r.Unit, ShouldEqual, info.unit)
+				So(fmt.Sprintf("%T", r.Data), ShouldEqual, info.dataType)
+				So(r.Tags, ShouldNotContainKey, "data_type")
+			}
+		})
+	})
+
+	Convey("Given namespace of a metric look up its description", t, func() {
+		info, ok := lookup(plugin.NewNamespace("intel", "iostat", "device", "sda", "rbytes_per_sec"), parser.NamingLegacy)
+		So(ok, ShouldBeTrue)
+		So(info, ShouldResemble, metricInfo{"The number of bytes read from the device per second", "B/s", typeFloat})
+
+		snake, ok := lookup(plugin.NewNamespace("intel", "iostat", "device", "sda", "read_bytes_per_sec"), parser.NamingSnakeCase)
+		So(ok, ShouldBeTrue)
+		So(snake, ShouldResemble, info)
+
+		info, ok = lookup(plugin.NewNamespace("intel", "iostat", "device", "sda", "anomaly", "%util"), parser.NamingLegacy)
+		So(ok, ShouldBeTrue)
+		So(info.unit, ShouldBeEmpty)
+
+		_, ok = lookup(plugin.NewNamespace("intel", "iostat", "device", "sda", "bad"), parser.NamingLegacy)
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("Given host root in config read host files under it", t, func() {
+		// hosts which differ only by sectors read by sda
+		roots := map[string]uint64{}
+		for _, sectors := range []uint64{1000, 2000} {
+			root, err := ioutil.TempDir("", "host")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(root)
+			So(os.Mkdir(filepath.Join(root, "proc"), 0755), ShouldBeNil)
+			line := fmt.Sprintf("   8       0 sda 11906 1431 %d 10816 53124 60453 2658786 89900 0 38728 100716\n", sectors)
+			So(ioutil.WriteFile(filepath.Join(root, "proc", "diskstats"), []byte(line), 0644), ShouldBeNil)
+			stat, err := ioutil.ReadFile(filepath.Join("testdata", "host", "proc", "stat"))
+			So(err, ShouldBeNil)
+			So(ioutil.WriteFile(filepath.Join(root, "proc", "stat"), stat, 0644), ShouldBeNil)
+			roots[root] = sectors
+		}
+
+		collector := NewIostatCollector(parser.DefaultPrefix)
+		collector.cmd = &mockCmdRunner{}
+		read := func(root string) (interface{}, error) {
+			result, err := collector.CollectMetrics([]plugin.Metric{
+				plugin.Metric{
+					Namespace: plugin.NewNamespace("intel", "iostat", "device", "sda", "counter", "sectors_read"),
+					// interval mode reads host files only
+					Config: plugin.Config{"HostRoot": root, "Mode": "interval"},
+				},
+			})
+			if err != nil || len(result) != 1 {
+				return nil, fmt.Errorf("collected %d metrics: %v", len(result), err)
+			}
+			return result[0].Data, nil
+		}
+
+		for root, sectors := range roots {
+			v, err := read(root)
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, sectors)
+		}
+
+		Convey("also in concurrent collections with different host roots", func() {
+			type collected struct {
+				root string
+				v    interface{}
+				err  error
+			}
+			results := make(chan collected)
+			for i := 0; i < 10; i++ {
+				for root := range roots {
+					go func(root string) {
+						v, err := read(root)
+						results <- collected{root, v, err}
+					}(root)
+				}
+			}
+			for i := 0; i < 10*len(roots); i++ {
+				r := <-results
+				So(r.err, ShouldBeNil)
+				So(r.v, ShouldEqual, roots[r.root])
+			}
+		})
+
+		Convey("resolving each host root once", func() {
+			for root := range roots {
+				cfg := plugin.Config{"HostRoot": root}
+				So(collector.hostFS(cfg), ShouldEqual, collector.hostFS(cfg))
+				So(collector.hostFS(cfg).Proc("diskstats"), ShouldEqual, filepath.Join(root, "proc", "diskstats"))
+				// sys is not mounted under the host root, the one seen by the plugin is used
+				So(collector.hostFS(cfg).Sys("block"), ShouldEqual, "/sys/block")
+			}
+		})
+	})
+
+	Convey("Given sampling config build iostat arguments", t, func() {
+		samples, interval, err := getSampling(plugin.Config{})
+		So(err, ShouldBeNil)
+		So(getArgs(plugin.Config{}, samples, interval), ShouldResemble,
+			[]string{"-c", "-d", "-p", "-g", "ALL", "-x", "-k", "-t", "-y", "1", "1"})
+
+		samples, interval, err = getSampling(plugin.Config{"Samples": int64(5), "SampleWindow": int64(10)})
+		So(err, ShouldBeNil)
+		So(samples, ShouldEqual, 5)
+		So(interval, ShouldEqual, 2)
+		So(strings.Join(getArgs(plugin.Config{}, samples, interval), " "), ShouldEndWith, "-y 2 5")
+
+		So(getArgs(plugin.Config{"ReportSinceBoot": true}, samples, interval), ShouldNotContain, "-y")
+
+		_, _, err = getSampling(plugin.Config{"Samples": int64(10), "SampleWindow": int64(5)})
+		So(err, ShouldNotBeNil)
+		_, _, err = getSampling(plugin.Config{"Samples": int64(0)})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Given multiple samples collect statistics over the sample window", t, func() {
+		cmd := &mockCmdRunner{out: mockSamplesCmdOut}
+		iostat := &Iostat{prefix: parser.DefaultPrefix, parser: parser.New(), cmd: cmd}
+		cfg := plugin.Config{"Samples": int64(3), "SampleWindow": int64(3)}
+		mts := []plugin.Metric{}
+		for _, ns := range []string{"await", "await/min", "await/max", "await/mean", "await/p50", "await/p95", "await/p99"} {
+			mts = append(mts, plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device", "sda").AddStaticElements(strings.Split(ns, "/")...),
+				Config:    cfg,
+			})
+		}
+		mts = append(mts, plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "iostat", "avg-cpu", "%user"),
+			Config:    cfg,
+		})
+
+		result, err := iostat.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(strings.Join(cmd.args, " "), ShouldEndWith, "-y 1 3")
+		So(cmd.timeout, ShouldEqual, 5*time.Second)
+
+		m := map[string]interface{}{}
+		for _, r := range result {
+			m[r.Namespace.String()] = r.Data
+		}
+		So(m, ShouldResemble, map[string]interface{}{
+			"/intel/iostat/device/sda/await":      168.0,
+			"/intel/iostat/device/sda/await/min":  1.0,
+			"/intel/iostat/device/sda/await/max":  500.0,
+			"/intel/iostat/device/sda/await/mean": 168.0,
+			"/intel/iostat/device/sda/await/p50":  3.0,
+			"/intel/iostat/device/sda/await/p95":  500.0,
+			"/intel/iostat/device/sda/await/p99":  500.0,
+			"/intel/iostat/avg-cpu/%user":         2.0,
+		})
+	})
+
+	Convey("Given interval mode compute statistics since the previous collection", t, func() {
+		root, err := ioutil.TempDir("", "iostat")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(root)
+		So(os.MkdirAll(filepath.Join(root, "proc"), 0755), ShouldBeNil)
+		writeProc := func(diskstats, stat string) {
+			So(ioutil.WriteFile(filepath.Join(root, "proc", "diskstats"), []byte(diskstats), 0644), ShouldBeNil)
+			So(ioutil.WriteFile(filepath.Join(root, "proc", "stat"), []byte(stat), 0644), ShouldBeNil)
+		}
+
+		iostat := NewIostatCollector(parser.DefaultPrefix)
+		iostat.cmd = &mockCmdRunner{}
+		cfg := plugin.Config{"Mode": "interval", "HostRoot": root}
+		mts := []plugin.Metric{
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "device").
+					AddDynamicElement("device_id", "Device ID").
+					AddStaticElement("w_per_sec"),
+				Config: cfg,
+			},
+			plugin.Metric{Namespace: plugin.NewNamespace("intel", "iostat", "device", "ALL", "%util"), Config: cfg},
+			plugin.Metric{Namespace: plugin.NewNamespace("intel", "iostat", "avg-cpu", "%idle"), Config: cfg},
+		}
+
+		writeProc("8 0 sda 0 0 0 0 100 0 800 0 0 500 0\n8 16 sdb 0 0 0 0 0 0 0 0 0 0 0\n",
+			"cpu  100 0 100 800 0 0 0 0 0 0\n")
+		result, err := iostat.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+		So(result, ShouldBeEmpty)
+
+		// pretend the previous collection was 2 seconds ago
+		iostat.snapshots[snapshotKey(mts)].taken = time.Now().Add(-2 * time.Second)
+		writeProc("8 0 sda 0 0 0 0 300 0 2400 0 0 1500 0\n8 16 sdb 0 0 0 0 0 0 0 0 0 0 0\n8 32 sdc 0 0 0 0 1 0 8 0 0 1 0\n",
+			"cpu  150 0 150 1100 0 0 0 0 0 0\n")
+		result, err = iostat.CollectMetrics(mts)
+		So(err, ShouldBeNil)
+
+		m := map[string]float64{}
+		for _, r := range result {
+			m[r.Namespace.String()] = r.Data.(float64)
+		}
+		So(len(m), ShouldEqual, 5)
+		So(m["/intel/iostat/device/sda/w_per_sec"], ShouldAlmostEqual, 100, 1)
+		So(m["/intel/iostat/device/ALL/w_per_sec"], ShouldAlmostEqual, 100, 1)
+		So(m["/intel/iostat/device/sdb/w_per_sec"], ShouldEqual, 0)
+		So(m["/intel/iostat/device/ALL/%util"], ShouldAlmostEqual, 25, 1)
+		So(m["/intel/iostat/avg-cpu/%idle"], ShouldEqual, 75)
+
+		Convey("skipping devices which counters were reset", func() {
+			writeProc("8 0 sda 0 0 0 0 1 0 8 0 0 1 0\n8 16 sdb 0 0 0 0 0 0 0 0 0 0 0\n8 32 sdc 0 0 0 0 2 0 16 0 0 2 0\n",
+				"cpu  200 0 200 1400 0 0 0 0 0 0\n")
+			result, err = iostat.CollectMetrics(mts)
+			So(err, ShouldBeNil)
+
+			namespaces := []string{}
+			for _, r := range result {
+				namespaces = append(namespaces, r.Namespace.String())
+			}
+			So(namespaces, ShouldNotContain, "/intel/iostat/device/sda/w_per_sec")
+			So(namespaces, ShouldContain, "/intel/iostat/device/sdb/w_per_sec")
+			So(namespaces, ShouldContain, "/intel/iostat/device/sdc/w_per_sec")
+		})
+
+		Convey("keeping snapshots of tasks apart", func() {
+			other := plugin.Config{"Mode": "interval", "HostRoot": root, "Task": "other"}
+			otherMts := make([]plugin.Metric, len(mts))
+			for i, mt := range mts {
+				otherMts[i] = plugin.Metric{Namespace: mt.Namespace, Config: other}
+			}
+			So(snapshotKey(otherMts), ShouldNotEqual, snapshotKey(mts))
+
+			result, err = iostat.CollectMetrics(otherMts)
+			So(err, ShouldBeNil)
+			So(result, ShouldBeEmpty)
+			So(iostat.snapshots, ShouldContainKey, snapshotKey(mts))
+			So(iostat.snapshots, ShouldContainKey, snapshotKey(otherMts))
+		})
+
+		Convey("forgetting snapshots which were not refreshed", func() {
+			key := snapshotKey(mts)
+			iostat.snapshots["stale"] = &snapshot{
+				taken:    time.Now().Add(-minSnapshotTTL - time.Minute),
+				interval: time.Second,
+			}
+			iostat.snapshots["slow"] = &snapshot{
+				taken:    time.Now().Add(-minSnapshotTTL - time.Minute),
+				interval: time.Hour,
+			}
+			_, err = iostat.CollectMetrics(mts)
+			So(err, ShouldBeNil)
+			So(iostat.snapshots, ShouldContainKey, key)
+			So(iostat.snapshots, ShouldContainKey, "slow")
+			So(iostat.snapshots, ShouldNotContainKey, "stale")
+			So(iostat.snapshots[key].interval, ShouldBeGreaterThan, 0)
+		})
+	})
+
+	Convey("Given invalid mode return an error", t, func() {
+		_, err := iostat.CollectMetrics([]plugin.Metric{
+			plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "iostat", "avg-cpu", "%idle"),
+				Config:    plugin.Config{"Mode": "bad"},
+			},
+		})
+		So(err, ShouldNotBeNil)
 	})
 
 	Convey("Get config policy", t, func() {
 		policy, err := iostat.GetConfigPolicy()
 		So(err, ShouldBeNil)
-		So(&policy, ShouldResemble, plugin.NewConfigPolicy())
+		So(&policy, ShouldNotResemble, plugin.NewConfigPolicy())
+		expected, err := configPolicy(parser.DefaultPrefix)
+		So(err, ShouldBeNil)
+		So(&policy, ShouldResemble, expected)
 	})
+
+	Convey("Given config validate values of its options", t, func() {
+		So(validateConfig(plugin.Config{}), ShouldBeNil)
+		So(validateConfig(plugin.Config{"Samples": int64(5), "SampleWindow": 10.0, "Units": "MB", "TopDevicesBy": "util_percent", "Naming": "snake_case"}), ShouldBeNil)
+
+		for _, cfg := range []plugin.Config{
+			{"Mode": "sar"},
+			{"Samples": int64(0)},
+			{"Samples": int64(4), "SampleWindow": int64(2)},
+			{"HealthAwaitSSD": 0.0},
+			{"HealthQueueGrowth": int64(0)},
+			{"AnomalyHalfLife": int64(-1)},
+			{"AnomalyWarmup": int64(-1)},
+			{"CaptureSize": int64(0)},
+			{"Units": "GB"},
+			{"Naming": "camelCase"},
+			{"TopDevicesBy": "svctm"},
+		} {
+			So(validateConfig(cfg), ShouldNotBeNil)
+		}
+
+		Convey("failing discovery and collection of metrics", func() {
+			cfg := plugin.Config{"AnomalyWarmup": int64(-1)}
+			_, err := iostat.GetMetricTypes(cfg)
+			So(err, ShouldNotBeNil)
+			_, err = iostat.CollectMetrics([]plugin.Metric{
+				plugin.Metric{Namespace: plugin.NewNamespace("intel", "iostat", "avg-cpu", "%idle"), Config: cfg},
+			})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+// benchmarkData returns data of a report with given number of devices
+func benchmarkData(devices int) map[string]interface{} {
+	iostat := NewIostatCollector(parser.DefaultPrefix)
+	data := map[string]interface{}{}
+	for _, name := range cpuMetrics {
+		data[iostat.joinNamespace(cpuMetric, name)] = 1.0
+	}
+	for i := 0; i < devices; i++ {
+		for _, c := range deviceColumns {
+			data[iostat.deviceNamespace(fmt.Sprintf("sd%d", i), c.name)] = 1.0
+		}
+	}
+	return data
+}
+
+// benchmarkRequest requests every device column of all devices
+func benchmarkRequest() []plugin.Namespace {
+	namespaces := []plugin.Namespace{}
+	for _, c := range deviceColumns {
+		namespaces = append(namespaces, plugin.NewNamespace("intel", "iostat", "device", "*", c.name))
+	}
+	return namespaces
+}
+
+// scanMatch matches every key of data against a regular expression built from
+// the requested namespace, as done before the index
+func scanMatch(ns plugin.Namespace, data map[string]interface{}) []string {
+	elems := make([]string, len(ns))
+	for i, e := range ns {
+		if e.Value == "*" {
+			elems[i] = "[^/]+"
+		} else {
+			elems[i] = regexp.QuoteMeta(e.Value)
+		}
+	}
+	reg := regexp.MustCompile("^/" + strings.Join(elems, "/") + "$")
+	matched := []string{}
+	for k := range data {
+		if reg.MatchString(k) {
+			matched = append(matched, k)
+		}
+	}
+	return matched
+}
+
+func BenchmarkMatchScan(b *testing.B) {
+	for _, devices := range []int{10, 100, 1000} {
+		data := benchmarkData(devices)
+		request := benchmarkRequest()
+		b.Run(fmt.Sprintf("devices=%d", devices), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				for _, ns := range request {
+					scanMatch(ns, data)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMatchIndex(b *testing.B) {
+	for _, devices := range []int{10, 100, 1000} {
+		data := benchmarkData(devices)
+		request := benchmarkRequest()
+		b.Run(fmt.Sprintf("devices=%d", devices), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				// the index is built once per collection
+				idx := newIndex(data)
+				for _, ns := range request {
+					idx.match(ns)
+				}
+			}
+		})
+	}
 }
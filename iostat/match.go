@@ -0,0 +1,134 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iostat
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+// isPattern checks whether the namespace element matches more than one value,
+// i.e. it is a wildcard "*" or a tuple like "(sda|sdb)"
+func isPattern(elem string) bool {
+	return elem == "*" || (strings.HasPrefix(elem, "(") && strings.HasSuffix(elem, ")"))
+}
+
+// matchElement checks whether the value matches the requested namespace element
+func matchElement(elem, value string) bool {
+	switch {
+	case elem == "*":
+		return true
+	case isPattern(elem):
+		for _, alt := range strings.Split(elem[1:len(elem)-1], "|") {
+			if alt == value {
+				return true
+			}
+		}
+		return false
+	}
+	return elem == value
+}
+
+// index is a tree of namespaces of collected data, element by element, so
+// metrics matching a requested namespace are found without scanning all data
+type index struct {
+	children map[string]*index
+	// leaf is set if there is data for the namespace ending at this node
+	leaf bool
+}
+
+// newIndex indexes namespaces of data
+func newIndex(data map[string]interface{}) *index {
+	root := &index{children: map[string]*index{}}
+	for k := range data {
+		node := root
+		for _, elem := range strings.Split(strings.TrimPrefix(k, "/"), "/") {
+			child, ok := node.children[elem]
+			if !ok {
+				child = &index{children: map[string]*index{}}
+				node.children[elem] = child
+			}
+			node = child
+		}
+		node.leaf = true
+	}
+	return root
+}
+
+// match returns sorted namespaces of data matching the requested one, which
+// may have wildcards and tuples in any position; matched namespaces keep
+// names and descriptions of elements of the requested one
+func (idx *index) match(ns plugin.Namespace) []plugin.Namespace {
+	matched := []plugin.Namespace{}
+	values := make([]string, len(ns))
+	idx.walk(ns, 0, values, &matched)
+	sort.Sort(byNamespace(matched))
+	return matched
+}
+
+func (idx *index) walk(ns plugin.Namespace, depth int, values []string, matched *[]plugin.Namespace) {
+	if depth == len(ns) {
+		if idx.leaf {
+			nsCopy := plugin.CopyNamespace(ns)
+			for i := range nsCopy {
+				nsCopy[i].Value = values[i]
+			}
+			*matched = append(*matched, nsCopy)
+		}
+		return
+	}
+
+	elem := ns[depth].Value
+	switch {
+	case elem == "*":
+		for value, child := range idx.children {
+			values[depth] = value
+			child.walk(ns, depth+1, values, matched)
+		}
+	case isPattern(elem):
+		for _, value := range strings.Split(elem[1:len(elem)-1], "|") {
+			if child, ok := idx.children[value]; ok {
+				values[depth] = value
+				child.walk(ns, depth+1, values, matched)
+			}
+		}
+	default:
+		if child, ok := idx.children[elem]; ok {
+			values[depth] = elem
+			child.walk(ns, depth+1, values, matched)
+		}
+	}
+}
+
+// byNamespace sorts namespaces by their elements
+type byNamespace []plugin.Namespace
+
+func (b byNamespace) Len() int      { return len(b) }
+func (b byNamespace) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byNamespace) Less(i, j int) bool {
+	for k := 0; k < len(b[i]) && k < len(b[j]); k++ {
+		if b[i][k].Value != b[j][k].Value {
+			return b[i][k].Value < b[j][k].Value
+		}
+	}
+	return len(b[i]) < len(b[j])
+}
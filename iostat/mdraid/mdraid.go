@@ -0,0 +1,210 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mdraid reads status of Linux software RAID (md) arrays from
+// /proc/mdstat and /sys/block/[array]/md
+package mdraid
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/sysfs"
+)
+
+const (
+	// MdMetric is a namespace element grouping status of an md array
+	MdMetric = "md"
+
+	// syncIdle is the sync action of an array which is not being synchronized
+	syncIdle = "idle"
+)
+
+// Metrics lists names of metrics returned by Array.Metrics
+var Metrics = []string{
+	"array_state",
+	"raid_disks",
+	"degraded",
+	"sync_action",
+	"sync_progress_percent",
+	"sync_speed_kB_per_sec",
+}
+
+var (
+	// disks matches number of disks in the array and number of working ones, e.g. "[3/2]"
+	disks = regexp.MustCompile(`\[(\d+)/(\d+)\]`)
+	// progress matches synchronization in progress, e.g. "recovery = 12.6%"
+	progress = regexp.MustCompile(`(resync|recovery|reshape|check|repair)\s*=\s*([\d.]+)%`)
+	// speed matches synchronization speed, e.g. "speed=123456K/sec"
+	speed = regexp.MustCompile(`speed=(\d+)K/sec`)
+)
+
+// Array holds status of an md array
+type Array struct {
+	Name string
+	// Level is a RAID level, e.g. raid1, unknown for inactive arrays
+	Level string
+	// Members are names of member devices, including failed and spare ones
+	Members []string
+	// State is array_state from sysfs (e.g. clean, active), or active/inactive from /proc/mdstat
+	State    string
+	Disks    uint64
+	Degraded uint64
+	// SyncAction is a synchronization in progress, e.g. resync or recover, idle if there is none
+	SyncAction   string
+	SyncProgress float64
+	// SyncSpeed is a synchronization speed in kB/s
+	SyncSpeed uint64
+}
+
+// Metrics returns status of the array by names listed in Metrics
+func (a *Array) Metrics() map[string]interface{} {
+	return map[string]interface{}{
+		"array_state":           a.State,
+		"raid_disks":            a.Disks,
+		"degraded":              a.Degraded,
+		"sync_action":           a.SyncAction,
+		"sync_progress_percent": a.SyncProgress,
+		"sync_speed_kB_per_sec": a.SyncSpeed,
+	}
+}
+
+// Tags returns RAID level and members of the array, to be added to metrics of the array device
+func (a *Array) Tags() map[string]string {
+	tags := map[string]string{"raid_members": strings.Join(a.Members, ",")}
+	if a.Level != "" {
+		tags["raid_level"] = a.Level
+	}
+	return tags
+}
+
+// Read returns md arrays listed in /proc/mdstat by their names; status
+// reported by /sys/block/[array]/md takes precedence over /proc/mdstat
+func Read(fs *hostfs.FS) (map[string]*Array, error) {
+	file, err := os.Open(fs.Proc("mdstat"))
+	if os.IsNotExist(err) {
+		// md driver is not loaded, so there are no arrays
+		return map[string]*Array{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	arrays := map[string]*Array{}
+	var array *Array
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) >= 3 && fields[1] == ":" && strings.HasPrefix(fields[0], "md"):
+			array = parseArray(fields)
+			arrays[array.Name] = array
+		case len(fields) == 0:
+			array = nil
+		case array != nil:
+			parseStatus(array, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, array := range arrays {
+		readSysfs(fs, array)
+	}
+	return arrays, nil
+}
+
+// parseArray parses the first line of an array, e.g. "md0 : active raid1 sdb1[1] sda1[0](F)"
+func parseArray(fields []string) *Array {
+	array := &Array{Name: fields[0], State: fields[2], SyncAction: syncIdle, SyncProgress: 100}
+	for _, f := range fields[3:] {
+		switch {
+		case strings.HasPrefix(f, "("):
+			// e.g. (read-only), (auto-read-only)
+		case strings.Contains(f, "["):
+			array.Members = append(array.Members, f[:strings.Index(f, "[")])
+		case array.Level == "":
+			array.Level = f
+		}
+	}
+	sort.Strings(array.Members)
+	return array
+}
+
+// parseStatus parses lines following the first line of an array
+func parseStatus(array *Array, line string) {
+	if m := disks.FindStringSubmatch(line); m != nil {
+		total, _ := strconv.ParseUint(m[1], 10, 64)
+		working, _ := strconv.ParseUint(m[2], 10, 64)
+		array.Disks = total
+		if total > working {
+			array.Degraded = total - working
+		}
+	}
+	if m := progress.FindStringSubmatch(line); m != nil {
+		array.SyncAction = m[1]
+		array.SyncProgress, _ = strconv.ParseFloat(m[2], 64)
+	}
+	if m := speed.FindStringSubmatch(line); m != nil {
+		array.SyncSpeed, _ = strconv.ParseUint(m[1], 10, 64)
+	}
+}
+
+// readSysfs updates status of the array with values found in sysfs
+func readSysfs(fs *hostfs.FS, array *Array) {
+	dir := fs.Sys("block", array.Name, MdMetric)
+	if v, err := sysfs.ReadString(dir + "/array_state"); err == nil {
+		array.State = v
+	}
+	if v, err := sysfs.ReadString(dir + "/level"); err == nil && v != "" {
+		array.Level = v
+	}
+	if v, err := sysfs.ReadUint(dir + "/raid_disks"); err == nil {
+		array.Disks = v
+	}
+	if v, err := sysfs.ReadUint(dir + "/degraded"); err == nil {
+		array.Degraded = v
+	}
+	if v, err := sysfs.ReadString(dir + "/sync_action"); err == nil {
+		array.SyncAction = v
+	}
+	if array.SyncAction == syncIdle {
+		array.SyncProgress, array.SyncSpeed = 100, 0
+		return
+	}
+	// sync_completed holds numbers of sectors done and to be done, e.g. "1024 / 4096"
+	if v, err := sysfs.ReadString(dir + "/sync_completed"); err == nil {
+		var done, total uint64
+		if n, _ := fmt.Sscanf(v, "%d / %d", &done, &total); n == 2 && total > 0 {
+			array.SyncProgress = 100 * float64(done) / float64(total)
+		}
+	}
+	if v, err := sysfs.ReadUint(dir + "/sync_speed"); err == nil {
+		array.SyncSpeed = v
+	}
+}
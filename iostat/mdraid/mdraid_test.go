@@ -0,0 +1,94 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdraid
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRead(t *testing.T) {
+	Convey("Given /proc/mdstat and sysfs read status of md arrays", t, func() {
+		arrays, err := Read(hostfs.New("../testdata/host"))
+		So(err, ShouldBeNil)
+		So(arrays, ShouldHaveLength, 3)
+
+		Convey("preferring status reported by sysfs", func() {
+			So(arrays["md127"], ShouldResemble, &Array{
+				Name:         "md127",
+				Level:        "raid5",
+				Members:      []string{"sdb1", "sdc1", "sdd1"},
+				State:        "active",
+				Disks:        3,
+				Degraded:     1,
+				SyncAction:   "recover",
+				SyncProgress: 100 * 246912 / 1953258496.0,
+				SyncSpeed:    123456,
+			})
+		})
+
+		Convey("falling back to /proc/mdstat", func() {
+			So(arrays["md0"], ShouldResemble, &Array{
+				Name:         "md0",
+				Level:        "raid1",
+				Members:      []string{"sda2", "sdb2"},
+				State:        "active",
+				Disks:        2,
+				Degraded:     1,
+				SyncAction:   "idle",
+				SyncProgress: 100,
+			})
+			So(arrays["md0"].Tags(), ShouldResemble, map[string]string{
+				"raid_level":   "raid1",
+				"raid_members": "sda2,sdb2",
+			})
+		})
+
+		Convey("including inactive arrays", func() {
+			So(arrays["md1"].State, ShouldEqual, "inactive")
+			So(arrays["md1"].Members, ShouldResemble, []string{"sdc2"})
+			So(arrays["md1"].Tags(), ShouldResemble, map[string]string{"raid_members": "sdc2"})
+		})
+	})
+
+	Convey("Given no /proc/mdstat return no arrays", t, func() {
+		root, err := ioutil.TempDir("", "mdraid")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(root)
+		So(os.Mkdir(filepath.Join(root, "proc"), 0755), ShouldBeNil)
+
+		arrays, err := Read(hostfs.New(root))
+		So(err, ShouldBeNil)
+		So(arrays, ShouldBeEmpty)
+	})
+
+	Convey("Given recovery in progress parse its status", t, func() {
+		array := &Array{}
+		parseStatus(array, "      [===>.................]  recovery = 17.5% (1/2) finish=10.0min speed=2048K/sec")
+		So(array.SyncAction, ShouldEqual, "recovery")
+		So(array.SyncProgress, ShouldEqual, 17.5)
+		So(array.SyncSpeed, ShouldEqual, 2048)
+	})
+}
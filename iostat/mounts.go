@@ -0,0 +1,88 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iostat
+
+import (
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/filesystem"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+// mountPointTag is a tag of filesystem metrics holding the mount point as is; it
+// is not named "mount" as the escaped mount point is the dynamic element named so
+const mountPointTag = "mount_point"
+
+// addFilesystems adds capacity and inode usage of filesystems mounted on the
+// host which are backed by given devices, or which type is listed in config
+// (e.g. nfs4 or tmpfs) unless metrics are limited to top N devices; returned
+// are tags of metrics of each filesystem by its escaped mount point
+func (iostat *Iostat) addFilesystems(fs *hostfs.FS, cfg plugin.Config, data map[string]interface{}, devs []string) (map[string]map[string]string, error) {
+	mounts, err := filesystem.Mounts(fs)
+	if err != nil {
+		return nil, err
+	}
+
+	backing := map[string]bool{}
+	for _, dev := range devs {
+		backing[dev] = true
+	}
+	types := map[string]bool{}
+	if getInt(cfg, cfgTopDevices, 0) <= 0 {
+		for _, t := range strings.Split(getString(cfg, cfgFilesystemTypes, ""), ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types[t] = true
+			}
+		}
+	}
+
+	tags := map[string]map[string]string{}
+	for _, m := range mounts {
+		if !backing[m.Device] && !types[m.Type] {
+			continue
+		}
+		stat, err := filesystem.Stat(fs.Path(m.Point))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"mount": m.Point,
+				"error": err,
+			}).Debug("failed to read filesystem statistics")
+			continue
+		}
+
+		mount := filesystem.Escape(m.Point)
+		for name, v := range stat {
+			data[iostat.filesystemNamespace(mount, name)] = v
+		}
+		device := m.Device
+		if device == "" {
+			device = m.Source
+		}
+		tags[mount] = map[string]string{"device": device, "fstype": m.Type, mountPointTag: m.Point}
+	}
+	return tags, nil
+}
+
+// filesystemNamespace returns namespace of the filesystem metric as a string
+func (iostat *Iostat) filesystemNamespace(mount, name string) string {
+	return plugin.NewNamespace(iostat.prefix.Vendor, iostat.prefix.Type, filesystem.FilesystemMetric, mount, name).String()
+}
@@ -0,0 +1,72 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iostat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/parser"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+// getNaming returns naming scheme of metrics selected in config
+func getNaming(cfg plugin.Config) (string, error) {
+	naming := getString(cfg, cfgNaming, parser.NamingLegacy)
+	if !parser.IsNaming(naming) {
+		return "", fmt.Errorf("Invalid %s %q (supported: %s)", cfgNaming, naming, strings.Join(parser.Namings, ", "))
+	}
+	return naming, nil
+}
+
+// renameMetrics renames static elements of namespaces of metric types, declared
+// under legacy names, into the naming scheme
+func renameMetrics(mts []plugin.Metric, naming string) {
+	for i := range mts {
+		for j, elem := range mts[i].Namespace {
+			if !elem.IsDynamic() {
+				mts[i].Namespace[j].Value = parser.Rename(elem.Value, naming)
+			}
+		}
+	}
+}
+
+// legacyName returns the legacy name of the metric named in the naming scheme,
+// the first one if versions of sysstat name the metric differently; metrics
+// are looked up by their legacy names, e.g. in the catalog
+func legacyName(name, naming string) string {
+	return parser.Legacy(name, naming)[0]
+}
+
+// uniqueMetrics removes metrics which namespaces repeat, as metrics of more
+// legacy names get the same name in other naming schemes
+func uniqueMetrics(mts []plugin.Metric) []plugin.Metric {
+	seen := map[string]bool{}
+	unique := mts[:0]
+	for _, mt := range mts {
+		ns := mt.Namespace.String()
+		if seen[ns] {
+			continue
+		}
+		seen[ns] = true
+		unique = append(unique, mt)
+	}
+	return unique
+}
@@ -0,0 +1,145 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nvme maps NVMe block devices to their controllers and reads
+// attributes of the controllers from sysfs
+package nvme
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/sysfs"
+)
+
+var (
+	// device matches NVMe namespaces, e.g. nvme0n1, their partitions, e.g. nvme0n1p2,
+	// and paths of namespaces through controllers with native multipath, e.g. nvme0c1n1
+	device = regexp.MustCompile(`^nvme\d+(c(\d+))?n\d+(p\d+)?$`)
+	// controller matches names of controllers
+	controller = regexp.MustCompile(`^nvme\d+$`)
+	// ebsVolume matches serial numbers of Amazon EBS volumes, e.g. vol0123456789abcdef0
+	ebsVolume = regexp.MustCompile(`^vol([0-9a-f]{8}|[0-9a-f]{17})$`)
+)
+
+// attributes of controllers published as tags, by file names
+var attributes = map[string]string{
+	"model":        "nvme_model",
+	"firmware_rev": "nvme_firmware",
+	"serial":       "nvme_serial",
+}
+
+// IsDevice checks whether the block device is an NVMe namespace, partition or path
+func IsDevice(dev string) bool {
+	return device.MatchString(dev)
+}
+
+// IsPath checks whether the block device is a path of a namespace through one
+// of its controllers, which native NVMe multipath hides behind the namespace
+func IsPath(dev string) bool {
+	m := device.FindStringSubmatch(dev)
+	return m != nil && m[1] != ""
+}
+
+// Tags returns controllers of the NVMe device and their model, firmware
+// revision, serial number and transport (pcie, tcp, rdma, fc, loop); a device
+// reachable through multiple controllers gets values of all of them joined
+// with commas. Serial numbers of Amazon EBS volumes are translated into volume IDs.
+func Tags(fs *hostfs.FS, dev string) (map[string]string, error) {
+	m := device.FindStringSubmatch(dev)
+	if m == nil {
+		return nil, errors.New("Not an NVMe device: " + dev)
+	}
+	// partitions have controllers of their namespace
+	dev = strings.TrimSuffix(dev, m[3])
+
+	ctrls, err := controllers(fs, dev)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string][]string{}
+	for _, ctrl := range ctrls {
+		dir := fs.Sys("class", "nvme", ctrl)
+		for file, tag := range attributes {
+			if v, err := sysfs.ReadString(filepath.Join(dir, file)); err == nil {
+				values[tag] = appendUnique(values[tag], v)
+			}
+		}
+		if v, err := sysfs.ReadString(filepath.Join(dir, "transport")); err == nil {
+			values["nvme_transport"] = appendUnique(values["nvme_transport"], v)
+		}
+	}
+
+	tags := map[string]string{"nvme_controller": strings.Join(ctrls, ",")}
+	for tag, v := range values {
+		tags[tag] = strings.Join(v, ",")
+	}
+	if m := ebsVolume.FindStringSubmatch(tags["nvme_serial"]); m != nil {
+		tags["volume_id"] = "vol-" + m[1]
+	}
+	return tags, nil
+}
+
+// controllers returns sorted names of controllers of the namespace; without
+// native multipath the device of the namespace is its controller, with it
+// the device is an NVMe subsystem listing its controllers
+func controllers(fs *hostfs.FS, dev string) ([]string, error) {
+	if m := device.FindStringSubmatch(dev); m != nil && m[1] != "" {
+		return []string{"nvme" + m[2]}, nil
+	}
+
+	path, err := filepath.EvalSymlinks(fs.Sys("class", "block", dev, "device"))
+	if err != nil {
+		return nil, err
+	}
+	if controller.MatchString(filepath.Base(path)) {
+		return []string{filepath.Base(path)}, nil
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	ctrls := []string{}
+	for _, e := range entries {
+		if controller.MatchString(e.Name()) {
+			ctrls = append(ctrls, e.Name())
+		}
+	}
+	if len(ctrls) == 0 {
+		return nil, errors.New("No NVMe controllers found for " + dev)
+	}
+	sort.Strings(ctrls)
+	return ctrls, nil
+}
+
+func appendUnique(values []string, v string) []string {
+	for _, existing := range values {
+		if existing == v {
+			return values
+		}
+	}
+	return append(values, v)
+}
@@ -0,0 +1,86 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nvme
+
+import (
+	"testing"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var fs = hostfs.New("../testdata/host")
+
+func TestNvme(t *testing.T) {
+	Convey("Given block device names recognise NVMe ones", t, func() {
+		So(IsDevice("nvme0n1"), ShouldBeTrue)
+		So(IsDevice("nvme0n1p2"), ShouldBeTrue)
+		So(IsDevice("nvme1c2n1"), ShouldBeTrue)
+		So(IsDevice("sda"), ShouldBeFalse)
+		So(IsDevice("nvme0"), ShouldBeFalse)
+
+		So(IsPath("nvme1c2n1"), ShouldBeTrue)
+		So(IsPath("nvme1n1"), ShouldBeFalse)
+		So(IsPath("sda"), ShouldBeFalse)
+	})
+
+	Convey("Given namespace read attributes of its controller", t, func() {
+		tags, err := Tags(fs, "nvme0n1")
+		So(err, ShouldBeNil)
+		So(tags, ShouldResemble, map[string]string{
+			"nvme_controller": "nvme0",
+			"nvme_model":      "Amazon Elastic Block Store",
+			"nvme_firmware":   "1.0",
+			"nvme_serial":     "vol0123456789abcdef0",
+			"nvme_transport":  "pcie",
+			"volume_id":       "vol-0123456789abcdef0",
+		})
+
+		Convey("using the namespace for its partitions", func() {
+			partTags, err := Tags(fs, "nvme0n1p1")
+			So(err, ShouldBeNil)
+			So(partTags, ShouldResemble, tags)
+		})
+	})
+
+	Convey("Given multipath namespace read attributes of all its controllers", t, func() {
+		tags, err := Tags(fs, "nvme1n1")
+		So(err, ShouldBeNil)
+		So(tags, ShouldResemble, map[string]string{
+			"nvme_controller": "nvme1,nvme2",
+			"nvme_model":      "Linux",
+			"nvme_firmware":   "5.15.0",
+			"nvme_serial":     "a1b2c3d4e5f6",
+			"nvme_transport":  "tcp,rdma",
+		})
+
+		Convey("and attributes of the controller of a path", func() {
+			tags, err := Tags(fs, "nvme1c2n1")
+			So(err, ShouldBeNil)
+			So(tags["nvme_controller"], ShouldEqual, "nvme2")
+			So(tags["nvme_transport"], ShouldEqual, "rdma")
+		})
+	})
+
+	Convey("Given other device return an error", t, func() {
+		_, err := Tags(fs, "sda")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,146 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"sort"
+	"strings"
+)
+
+// naming schemes of metrics
+const (
+	// NamingLegacy keeps names as reported by iostat, e.g. %util or avgrq-sz
+	NamingLegacy = "legacy"
+	// NamingSnakeCase names metrics with lower case letters, digits and underscores only
+	NamingSnakeCase = "snake_case"
+)
+
+// Namings lists supported naming schemes
+var Namings = []string{NamingLegacy, NamingSnakeCase}
+
+// snakeCase maps legacy names of metrics, as reported by iostat after
+// replaceByPerSec and by other sources of the plugin, to snake_case names;
+// names not listed here are snake_case already. Names of the same metric
+// reported by different versions of sysstat get the same snake_case name
+var snakeCase = map[string]string{
+	"avg-cpu": "avg_cpu",
+	"%user":   "user_percent",
+	"%nice":   "nice_percent",
+	"%system": "system_percent",
+	"%iowait": "iowait_percent",
+	"%steal":  "steal_percent",
+	"%idle":   "idle_percent",
+
+	"rrqm_per_sec":   "read_merged_per_sec",
+	"wrqm_per_sec":   "write_merged_per_sec",
+	"drqm_per_sec":   "discard_merged_per_sec",
+	"%rrqm":          "read_merged_percent",
+	"%wrqm":          "write_merged_percent",
+	"%drqm":          "discard_merged_percent",
+	"r_per_sec":      "reads_per_sec",
+	"w_per_sec":      "writes_per_sec",
+	"d_per_sec":      "discards_per_sec",
+	"f_per_sec":      "flushes_per_sec",
+	"rkB_per_sec":    "read_kb_per_sec",
+	"wkB_per_sec":    "write_kb_per_sec",
+	"dkB_per_sec":    "discard_kb_per_sec",
+	"rbytes_per_sec": "read_bytes_per_sec",
+	"wbytes_per_sec": "write_bytes_per_sec",
+	"dbytes_per_sec": "discard_bytes_per_sec",
+	"rMB_per_sec":    "read_mb_per_sec",
+	"wMB_per_sec":    "write_mb_per_sec",
+	"dMB_per_sec":    "discard_mb_per_sec",
+	"avgrq-sz":       "avg_request_size_sectors",
+	"areq-sz":        "avg_request_size_kb",
+	"rareq-sz":       "avg_read_request_size_kb",
+	"wareq-sz":       "avg_write_request_size_kb",
+	"dareq-sz":       "avg_discard_request_size_kb",
+	"avgrq_bytes":    "avg_request_size_bytes",
+	"rareq_bytes":    "avg_read_request_size_bytes",
+	"wareq_bytes":    "avg_write_request_size_bytes",
+	"dareq_bytes":    "avg_discard_request_size_bytes",
+	"avgqu-sz":       "avg_queue_length",
+	"aqu-sz":         "avg_queue_length",
+	"await":          "await_ms",
+	"r_await":        "read_await_ms",
+	"w_await":        "write_await_ms",
+	"d_await":        "discard_await_ms",
+	"f_await":        "flush_await_ms",
+	"svctm":          "service_time_ms",
+	"%util":          "util_percent",
+
+	"sync_speed_kB_per_sec": "sync_speed_kb_per_sec",
+	"sync_speed_MB_per_sec": "sync_speed_mb_per_sec",
+}
+
+// legacyNames maps snake_case names back to legacy ones
+var legacyNames = map[string][]string{}
+
+func init() {
+	for legacy, snake := range snakeCase {
+		legacyNames[snake] = append(legacyNames[snake], legacy)
+	}
+	for _, names := range legacyNames {
+		sort.Strings(names)
+	}
+}
+
+// metricNames returns names of metrics in the naming scheme for columns printed by iostat
+func metricNames(columns []string, naming string) []string {
+	names := replaceByPerSec(columns)
+	for i, name := range names {
+		names[i] = Rename(name, naming)
+	}
+	return names
+}
+
+// replacePerSec turns "/s" into "_per_sec"
+func replaceByPerSec(slice []string) []string {
+	for i, str := range slice {
+		slice[i] = strings.Replace(str, "/s", "_per_sec", 1)
+	}
+	return slice
+}
+
+// IsNaming checks whether the naming scheme is supported
+func IsNaming(naming string) bool {
+	return naming == NamingLegacy || naming == NamingSnakeCase
+}
+
+// Rename returns name of the metric, given by its legacy name, in the naming scheme
+func Rename(name, naming string) string {
+	if naming == NamingSnakeCase {
+		if snake, ok := snakeCase[name]; ok {
+			return snake
+		}
+	}
+	return name
+}
+
+// Legacy returns legacy names of the metric named in the naming scheme, there
+// are more of them if versions of sysstat name the metric differently
+func Legacy(name, naming string) []string {
+	if naming == NamingSnakeCase {
+		if legacy, ok := legacyNames[name]; ok {
+			return legacy
+		}
+	}
+	return []string{name}
+}
@@ -31,13 +31,43 @@ import (
 	log "github.com/Sirupsen/logrus"
 )
 
+const (
+	defaultEmptyTokenAcceptance = 5
+)
+
+// NsVendor and NsType are the default first two elements of namespaces of all metrics
 const (
 	NsVendor = "intel"
 	NsType   = "iostat"
-
-	defaultEmptyTokenAcceptance = 5
 )
 
+// Prefix holds the first two elements of namespaces of all metrics, forks may
+// publish metrics under their own vendor and type
+type Prefix struct {
+	Vendor string
+	Type   string
+}
+
+// DefaultPrefix is the prefix of namespaces unless another one is given when the plugin starts
+var DefaultPrefix = Prefix{Vendor: NsVendor, Type: NsType}
+
+// NewPrefix returns prefix of namespaces with vendor and type elements, empty values keep the defaults
+func NewPrefix(vendor, nsType string) (Prefix, error) {
+	prefix := DefaultPrefix
+	for _, elem := range []string{vendor, nsType} {
+		if strings.ContainsAny(elem, "/*()| \t") {
+			return prefix, fmt.Errorf("Invalid namespace element %q", elem)
+		}
+	}
+	if vendor != "" {
+		prefix.Vendor = vendor
+	}
+	if nsType != "" {
+		prefix.Type = nsType
+	}
+	return prefix, nil
+}
+
 type parser struct {
 	// this structure is used in parsing iostat command output
 	firstLine   bool // set true if next interval is exepected
@@ -52,22 +82,31 @@ type parser struct {
 	stats  []string // slice of statistics, after parsing process it's equivalent to IOSTAT.keys
 	values []string // slice of statictics' values
 
-	keys []string
-	data map[string]float64
+	keys    []string
+	reports []map[string]float64 // values of each report, iostat prints one report per interval
+
+	prefix Prefix // first two elements of namespaces
+	naming string // naming scheme of metrics, see Rename
 }
 
 func New() *parser {
 	return &parser{
-		keys: []string{},
-		data: map[string]float64{},
+		keys:    []string{},
+		reports: []map[string]float64{},
 	}
 }
 
-func (p *parser) Parse(reader io.Reader) ([]string, map[string]float64, error) {
+// Parse returns namespaces of metrics, under the prefix and named in the naming
+// scheme, and values of each report found in iostat output
+func (p *parser) Parse(reader io.Reader, prefix Prefix, naming string) ([]string, []map[string]float64, error) {
+	// state of parsing is kept per output, so outputs can be parsed concurrently
+	state := New()
+	state.prefix = prefix
+	state.naming = naming
 	scanner := bufio.NewScanner(reader)
 
 	for scanner.Scan() {
-		err := p.parse(scanner.Text())
+		err := state.parse(scanner.Text())
 		if err != nil {
 			log.WithFields(log.Fields{
 				"line":  scanner.Text(),
@@ -76,11 +115,12 @@ func (p *parser) Parse(reader io.Reader) ([]string, map[string]float64, error) {
 		}
 	}
 
-	return p.keys, p.data, nil
+	return state.keys, state.reports, nil
 }
 
 func (p *parser) parse(data string) error {
-	line := strings.Fields(data)
+	// no-break spaces separate thousands in some locales, not columns
+	line := strings.Fields(strings.NewReplacer("\u00a0", "", "\u202f", "").Replace(data))
 	if len(line) == 0 {
 		// slice "line" is empty
 		p.emptyTokens++
@@ -106,8 +146,8 @@ func (p *parser) parse(data string) error {
 
 	if strings.HasSuffix(line[0], ":") {
 		if len(line) > 1 {
-			p.statType = strings.ToLower(strings.TrimSuffix(line[0], ":"))
-			p.statNames = replaceByPerSec(line[1:])
+			p.statType = Rename(strings.ToLower(strings.TrimSuffix(line[0], ":")), p.naming)
+			p.statNames = metricNames(line[1:], p.naming)
 			return nil
 		}
 	}
@@ -148,7 +188,7 @@ func (p *parser) parse(data string) error {
 
 			p.keys = make([]string, len(p.stats))
 			for i, s := range p.stats {
-				p.keys[i] = joinNamespace(createNamespace(s))
+				p.keys[i] = joinNamespace(p.createNamespace(s))
 			}
 		}
 
@@ -157,19 +197,71 @@ func (p *parser) parse(data string) error {
 			return errors.New("invalid parsing iostat output")
 		}
 
+		report := map[string]float64{}
 		for i, val := range p.values {
-			v, err := strconv.ParseFloat(strings.TrimSpace(strings.Replace(val, ",", ".", 1)), 64)
+			v, err := ParseNumber(val)
 			if err == nil {
-				p.data[p.keys[i]] = v
+				report[p.keys[i]] = v
 			} else {
 				fmt.Fprintln(os.Stderr, "invalid metric value", err)
 			}
 		}
+		p.reports = append(p.reports, report)
 	}
 
 	return nil
 }
 
+// ParseNumber parses a value printed by iostat, also in locales using decimal
+// comma and separators of thousands like "1.234,56", "1 234,56" or "1,234.56".
+// The last comma or dot is taken as decimal separator unless it repeats, like
+// in "1.234.567", or it is the only one and separates thousands: iostat prints
+// values with two decimals, so a lone separator followed by three digits, like
+// in "1,234", separates thousands if the integer part has one to three digits
+// and does not start with zero ("0,125" or "1234,567" are decimal)
+func ParseNumber(val string) (float64, error) {
+	val = strings.Map(func(r rune) rune {
+		switch r {
+		case '\'', '\u00a0', '\u202f':
+			// separators of thousands
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(val))
+
+	i := strings.LastIndexAny(val, ".,")
+	if i >= 0 {
+		sep := val[i : i+1]
+		integer, fraction := val[:i], val[i+1:]
+		if strings.Contains(integer, sep) || isThousands(integer, fraction) {
+			// separator of thousands only, like "1.234.567" or "1,234"
+			integer, fraction = val, ""
+		}
+		integer = strings.NewReplacer(".", "", ",", "").Replace(integer)
+		val = integer
+		if fraction != "" {
+			val += "." + fraction
+		}
+	}
+	return strconv.ParseFloat(val, 64)
+}
+
+// isThousands checks whether the only separator in a value, between its integer
+// part and fraction, separates thousands, see ParseNumber
+func isThousands(integer, fraction string) bool {
+	integer = strings.TrimPrefix(integer, "-")
+	if len(fraction) != 3 || len(integer) < 1 || len(integer) > 3 || integer[0] == '0' {
+		return false
+	}
+	for _, r := range integer + fraction {
+		if r < '0' || r > '9' {
+			// the other separator precedes, like in "1.234,567"
+			return false
+		}
+	}
+	return true
+}
+
 // returns version of iostat as [3]int
 func (p *parser) ParseVersion(versionString string) ([]int64, error) {
 	//verionString should be like "systat version %d.%d.%d \n[...]"
@@ -195,19 +287,11 @@ func (p *parser) ParseVersion(versionString string) ([]int64, error) {
 	return version, nil
 }
 
-// replacePerSec turns "/s" into "_per_sec"
-func replaceByPerSec(slice []string) []string {
-	for i, str := range slice {
-		slice[i] = strings.Replace(str, "/s", "_per_sec", 1)
-	}
-	return slice
-}
-
 func joinNamespace(ns []string) string {
 	return "/" + strings.Join(ns, "/")
 }
 
 // createNamespace returns namespace slice of strings composed from: vendor, type and ceph-daemon name
-func createNamespace(name string) []string {
-	return []string{NsVendor, NsType, name}
+func (p *parser) createNamespace(name string) []string {
+	return []string{p.prefix.Vendor, p.prefix.Type, name}
 }
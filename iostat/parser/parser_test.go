@@ -0,0 +1,194 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParser(t *testing.T) {
+	Convey("Given iostat output of hosts in different locales", t, func() {
+		for _, tc := range []struct {
+			locale  string
+			devices int
+			reports int
+			// values of the last report
+			values map[string]float64
+		}{
+			{"C", 3, 1, map[string]float64{
+				"/intel/iostat/avg-cpu/%idle":          98.15,
+				"/intel/iostat/device/sda/wkB_per_sec": 1234.56,
+				"/intel/iostat/device/ALL/await":       3.75,
+			}},
+			{"de_DE", 3, 1, map[string]float64{
+				"/intel/iostat/avg-cpu/%idle":          98.15,
+				"/intel/iostat/device/sda/wkB_per_sec": 1234.56,
+				"/intel/iostat/device/ALL/await":       3.75,
+			}},
+			{"fr_FR", 5, 1, map[string]float64{
+				"/intel/iostat/avg-cpu/%iowait":            1.93,
+				"/intel/iostat/device/nvme0n1/rkB_per_sec": 6104.88,
+				"/intel/iostat/device/dm-0/w_await":        1.30,
+				"/intel/iostat/device/ALL/rkB_per_sec":     18314.38,
+			}},
+			// values grouped with separators of thousands, like "1,204.50" or "1.234,00"
+			{"en_US", 4, 1, map[string]float64{
+				"/intel/iostat/avg-cpu/%idle":              93.37,
+				"/intel/iostat/device/nvme0n1/r_per_sec":   1204.5,
+				"/intel/iostat/device/nvme0n1/wkB_per_sec": 12345.67,
+				"/intel/iostat/device/md0/w_per_sec":       1720.5,
+				"/intel/iostat/device/ALL/rkB_per_sec":     192220,
+			}},
+			{"de_AT", 3, 1, map[string]float64{
+				"/intel/iostat/avg-cpu/%iowait":        7.4,
+				"/intel/iostat/device/sda/rkB_per_sec": 39680,
+				"/intel/iostat/device/sda/wkB_per_sec": 1234,
+				"/intel/iostat/device/sdb/wkB_per_sec": 1230.5,
+				"/intel/iostat/device/ALL/await":       16.79,
+			}},
+			{"pl_PL", 3, 2, map[string]float64{
+				"/intel/iostat/avg-cpu/%steal":       1.01,
+				"/intel/iostat/device/vda/avgqu-sz":  2.87,
+				"/intel/iostat/device/vda/%util":     96,
+				"/intel/iostat/device/vdb/r_per_sec": 0,
+			}},
+		} {
+			tc := tc
+			Convey("parse values localized in "+tc.locale, func() {
+				file, err := os.Open(filepath.Join("..", "testdata", "iostat", tc.locale+".txt"))
+				So(err, ShouldBeNil)
+				defer file.Close()
+
+				keys, reports, err := New().Parse(file, DefaultPrefix, NamingLegacy)
+				So(err, ShouldBeNil)
+				So(keys, ShouldHaveLength, 6+tc.devices*13)
+				So(reports, ShouldHaveLength, tc.reports)
+				last := reports[len(reports)-1]
+				So(last, ShouldHaveLength, len(keys))
+				for key, value := range tc.values {
+					So(last[key], ShouldEqual, value)
+				}
+			})
+		}
+	})
+
+	Convey("Given values with separators of thousands parse them", t, func() {
+		for val, expected := range map[string]float64{
+			"0":           0,
+			"12.50":       12.5,
+			"12,50":       12.5,
+			" 3,75 ":      3.75,
+			"1.234,56":    1234.56,
+			"1,234.56":    1234.56,
+			"1 234,56":    1234.56,
+			"1 234 567,8": 1234567.8,
+			"1'234.5":     1234.5,
+			"1.234.567":   1234567,
+			"1,234,567":   1234567,
+			// a lone separator followed by three digits separates thousands,
+			// as iostat prints values with two decimals
+			"1,234":    1234,
+			"1.234":    1234,
+			"-12.345":  -12345,
+			"123,456":  123456,
+			"1,234.00": 1234,
+			// unless the integer part cannot start a group
+			"0,125":    0.125,
+			"1234,567": 1234.567,
+			"12,3456":  12.3456,
+			"1.5":      1.5,
+		} {
+			v, err := ParseNumber(val)
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, expected)
+		}
+		for _, val := range []string{"", "n/a"} {
+			_, err := ParseNumber(val)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Given no-break spaces in values do not split columns", t, func() {
+		out := "avg-cpu:  %user   %nice %system %iowait  %steal   %idle\n" +
+			"           1,25    0,00    0,50    0,10    0,00   98,15\n" +
+			"Device:         rkB/s    wkB/s\n" +
+			"sda              1 024,00    12 345,50\n" +
+			" ALL             1 024,00    12 345,50\n"
+		keys, reports, err := New().Parse(strings.NewReader(out), DefaultPrefix, NamingLegacy)
+		So(err, ShouldBeNil)
+		So(keys, ShouldHaveLength, 10)
+		So(reports, ShouldHaveLength, 1)
+		So(reports[0]["/intel/iostat/device/sda/rkB_per_sec"], ShouldEqual, 1024)
+		So(reports[0]["/intel/iostat/device/ALL/wkB_per_sec"], ShouldEqual, 12345.5)
+	})
+
+	Convey("Given namespace prefix parse metrics under it", t, func() {
+		prefix, err := NewPrefix("acme", "")
+		So(err, ShouldBeNil)
+		So(prefix, ShouldResemble, Prefix{Vendor: "acme", Type: "iostat"})
+
+		keys, _, err := New().Parse(strings.NewReader("avg-cpu:  %user\n 1.00\nDevice: await\n ALL 1.00\n"), prefix, NamingLegacy)
+		So(err, ShouldBeNil)
+		So(keys, ShouldResemble, []string{"/acme/iostat/avg-cpu/%user", "/acme/iostat/device/ALL/await"})
+		So(DefaultPrefix, ShouldResemble, Prefix{Vendor: "intel", Type: "iostat"})
+
+		Convey("refusing elements which are not valid in namespaces", func() {
+			_, err := NewPrefix("acme/corp", "")
+			So(err, ShouldNotBeNil)
+			_, err = NewPrefix("", "*")
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given snake_case naming name metrics in it while parsing", t, func() {
+		out := "avg-cpu:  %user   %idle\n" +
+			"           1.25   98.15\n" +
+			"Device:         rkB/s    aqu-sz  %util\n" +
+			"sda              1.00      0.50   2.00\n" +
+			" ALL             1.00      0.50   2.00\n"
+		keys, reports, err := New().Parse(strings.NewReader(out), DefaultPrefix, NamingSnakeCase)
+		So(err, ShouldBeNil)
+		So(keys, ShouldResemble, []string{
+			"/intel/iostat/avg_cpu/user_percent",
+			"/intel/iostat/avg_cpu/idle_percent",
+			"/intel/iostat/device/sda/read_kb_per_sec",
+			"/intel/iostat/device/sda/avg_queue_length",
+			"/intel/iostat/device/sda/util_percent",
+			"/intel/iostat/device/ALL/read_kb_per_sec",
+			"/intel/iostat/device/ALL/avg_queue_length",
+			"/intel/iostat/device/ALL/util_percent",
+		})
+		So(reports[0]["/intel/iostat/device/sda/read_kb_per_sec"], ShouldEqual, 1)
+	})
+
+	Convey("Given names of a metric in versions of sysstat rename them the same", t, func() {
+		So(Rename("avgqu-sz", NamingSnakeCase), ShouldEqual, "avg_queue_length")
+		So(Rename("aqu-sz", NamingSnakeCase), ShouldEqual, "avg_queue_length")
+		So(Rename("aqu-sz", NamingLegacy), ShouldEqual, "aqu-sz")
+		So(Legacy("avg_queue_length", NamingSnakeCase), ShouldResemble, []string{"aqu-sz", "avgqu-sz"})
+		So(Legacy("util_percent", NamingSnakeCase), ShouldResemble, []string{"%util"})
+		So(Legacy("aqu-sz", NamingLegacy), ShouldResemble, []string{"aqu-sz"})
+	})
+}
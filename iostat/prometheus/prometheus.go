@@ -0,0 +1,307 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+const (
+	contentTypeText        = "text/plain; version=0.0.4; charset=utf-8"
+	contentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+	typeGauge   = "gauge"
+	typeCounter = "counter"
+
+	// counterElement is a namespace element grouping cumulative counters
+	counterElement = "counter"
+	// counterSuffix ends names of counter samples
+	counterSuffix = "_total"
+	// availableTag marks metric types which the collector does not report on the host
+	availableTag = "available"
+)
+
+type collects interface {
+	CollectMetrics([]plugin.Metric) ([]plugin.Metric, error)
+	GetMetricTypes(plugin.Config) ([]plugin.Metric, error)
+}
+
+// Handler serves metrics of a Snap collector in Prometheus exposition format
+type Handler struct {
+	collector collects
+	config    plugin.Config
+	cacheTTL  time.Duration
+
+	mutex     sync.Mutex
+	mts       []plugin.Metric
+	cached    []plugin.Metric
+	collected time.Time
+}
+
+// NewHandler returns handler which collects metrics on scrape, reusing
+// results of a previous collection which is not older than cacheTTL
+func NewHandler(collector collects, config plugin.Config, cacheTTL time.Duration) *Handler {
+	return &Handler{
+		collector: collector,
+		config:    config,
+		cacheTTL:  cacheTTL,
+	}
+}
+
+// ServeHTTP writes all metrics exposed by the collector
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mts, err := h.collect()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("failed to collect metrics")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+	buf := &bytes.Buffer{}
+	Write(buf, mts, openMetrics)
+
+	if openMetrics {
+		w.Header().Set("Content-Type", contentTypeOpenMetrics)
+	} else {
+		w.Header().Set("Content-Type", contentTypeText)
+	}
+	w.Write(buf.Bytes())
+}
+
+// collect returns cached metrics or runs a new collection when cache has expired
+func (h *Handler) collect() ([]plugin.Metric, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.cached != nil && time.Since(h.collected) < h.cacheTTL {
+		return h.cached, nil
+	}
+
+	if h.mts == nil {
+		mts, err := h.collector.GetMetricTypes(h.config)
+		if err != nil {
+			return nil, err
+		}
+		// metric types which cannot be collected are not requested on each scrape
+		h.mts = []plugin.Metric{}
+		for _, mt := range mts {
+			if mt.Tags[availableTag] == "false" {
+				continue
+			}
+			mt.Config = h.config
+			h.mts = append(h.mts, mt)
+		}
+	}
+
+	mts, err := h.collector.CollectMetrics(h.mts)
+	if err != nil {
+		return nil, err
+	}
+	h.cached = mts
+	h.collected = time.Now()
+	return mts, nil
+}
+
+type sample struct {
+	labels string
+	value  float64
+}
+
+type family struct {
+	help    string
+	typ     string
+	samples []sample
+}
+
+// Write writes metrics in Prometheus text format, or in OpenMetrics text format
+// if openMetrics is set; metrics which data is not numeric are skipped
+func Write(w io.Writer, mts []plugin.Metric, openMetrics bool) {
+	families := map[string]*family{}
+	for _, mt := range mts {
+		value, ok := toFloat(mt.Data)
+		if !ok {
+			continue
+		}
+		name, labels := MetricName(mt)
+		f, ok := families[name]
+		if !ok {
+			f = &family{help: mt.Description, typ: typeGauge}
+			if isCounter(mt) {
+				f.typ = typeCounter
+			}
+			families[name] = f
+		}
+		f.samples = append(f.samples, sample{labels: formatLabels(labels), value: value})
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := families[name]
+		// in OpenMetrics the "_total" suffix belongs to samples of a counter only,
+		// not to the name of its family
+		family := name
+		if openMetrics && f.typ == typeCounter {
+			family = strings.TrimSuffix(name, counterSuffix)
+		}
+		if f.help != "" {
+			fmt.Fprintf(w, "# HELP %s %s\n", family, escapeHelp(f.help))
+		}
+		fmt.Fprintf(w, "# TYPE %s %s\n", family, f.typ)
+		for _, s := range f.samples {
+			fmt.Fprintf(w, "%s%s %s\n", name, s.labels, strconv.FormatFloat(s.value, 'g', -1, 64))
+		}
+	}
+	if openMetrics {
+		fmt.Fprintln(w, "# EOF")
+	}
+}
+
+// isCounter checks whether the metric is a cumulative counter, counters are
+// grouped under "counter" namespace element
+func isCounter(mt plugin.Metric) bool {
+	for _, e := range mt.Namespace {
+		if !e.IsDynamic() && e.Value == counterElement {
+			return true
+		}
+	}
+	return false
+}
+
+// MetricName translates Snap namespace into Prometheus metric name; dynamic
+// elements of the namespace and tags of the metric are turned into labels,
+// e.g. /intel/iostat/device/sda/%util becomes intel_iostat_device_util_percent{device="sda"};
+// names of counters get "_total" suffix
+func MetricName(mt plugin.Metric) (string, map[string]string) {
+	labels := map[string]string{}
+	dynamic := map[string]bool{}
+	parts := []string{}
+	for _, e := range mt.Namespace {
+		if e.IsDynamic() {
+			labels[sanitize(strings.TrimSuffix(e.Name, "_id"))] = e.Value
+			dynamic[e.Value] = true
+			continue
+		}
+		parts = append(parts, sanitizeElement(e.Value))
+	}
+	for k, v := range mt.Tags {
+		// tags repeating value of a dynamic element (like "dev") are redundant
+		if dynamic[v] {
+			continue
+		}
+		k = sanitize(k)
+		if _, ok := labels[k]; !ok {
+			labels[k] = v
+		}
+	}
+	name := strings.Join(parts, "_")
+	if isCounter(mt) {
+		name += counterSuffix
+	}
+	return name, labels
+}
+
+// sanitizeElement turns namespace element into valid part of metric name,
+// leading "%" is translated into "_percent" suffix
+func sanitizeElement(s string) string {
+	if strings.HasPrefix(s, "%") {
+		s = strings.TrimPrefix(s, "%") + "_percent"
+	}
+	return sanitize(s)
+}
+
+// sanitize replaces characters which are not allowed in metric and label names
+func sanitize(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=\"" + escapeLabel(labels[k]) + "\""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// toFloat converts metric data into float64
+func toFloat(data interface{}) (float64, bool) {
+	switch v := data.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
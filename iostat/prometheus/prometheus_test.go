@@ -0,0 +1,159 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type mockCollector struct {
+	collections int
+	requested   []plugin.Metric
+}
+
+func (c *mockCollector) GetMetricTypes(_ plugin.Config) ([]plugin.Metric, error) {
+	return []plugin.Metric{
+		plugin.Metric{Namespace: plugin.NewNamespace("intel", "iostat", "avg-cpu", "%idle")},
+		plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "iostat", "device").
+				AddDynamicElement("device_id", "Device ID").
+				AddStaticElement("aqu-sz"),
+			Tags: map[string]string{"available": "false"},
+		},
+	}, nil
+}
+
+func (c *mockCollector) CollectMetrics(mts []plugin.Metric) ([]plugin.Metric, error) {
+	c.collections++
+	c.requested = mts
+	return []plugin.Metric{
+		plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "iostat", "avg-cpu", "%idle"),
+			Data:      99.5,
+		},
+		plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "iostat", "device").
+				AddDynamicElement("device_id", "Device ID").
+				AddStaticElement("avgrq-sz"),
+			Data: 8.0,
+		},
+	}, nil
+}
+
+func deviceMetric(dev, name string, data interface{}) plugin.Metric {
+	ns := plugin.NewNamespace("intel", "iostat", "device").
+		AddDynamicElement("device_id", "Device ID").
+		AddStaticElements(strings.Split(name, "/")...)
+	ns[3].Value = dev
+	return plugin.Metric{Namespace: ns, Data: data, Tags: map[string]string{"dev": dev}}
+}
+
+func TestPrometheus(t *testing.T) {
+	Convey("Given device metric translate its namespace", t, func() {
+		name, labels := MetricName(deviceMetric("sda", "%util", 1.0))
+		So(name, ShouldEqual, "intel_iostat_device_util_percent")
+		So(labels, ShouldResemble, map[string]string{"device": "sda"})
+
+		name, _ = MetricName(deviceMetric("sda", "avgqu-sz", 1.0))
+		So(name, ShouldEqual, "intel_iostat_device_avgqu_sz")
+
+		name, _ = MetricName(deviceMetric("sda", "counter/reads_completed", uint64(1)))
+		So(name, ShouldEqual, "intel_iostat_device_counter_reads_completed_total")
+
+		ns := plugin.NewNamespace("intel", "iostat", "filesystem").
+			AddDynamicElement("mount", "Mount point").
+			AddStaticElement("bytes_used")
+		ns[3].Value = "var-lib"
+		name, labels = MetricName(plugin.Metric{Namespace: ns, Tags: map[string]string{"device": "sda1", "mount_point": "/var/lib"}})
+		So(name, ShouldEqual, "intel_iostat_filesystem_bytes_used")
+		So(labels, ShouldResemble, map[string]string{"mount": "var-lib", "device": "sda1", "mount_point": "/var/lib"})
+
+		name, labels = MetricName(plugin.Metric{Namespace: plugin.NewNamespace("intel", "iostat", "avg-cpu", "%iowait")})
+		So(name, ShouldEqual, "intel_iostat_avg_cpu_iowait_percent")
+		So(labels, ShouldBeEmpty)
+	})
+
+	Convey("Given metrics write them in text format", t, func() {
+		mts := []plugin.Metric{
+			deviceMetric("sdb", "await", 1.5),
+			deviceMetric("sda", "await", 2.0),
+			deviceMetric("sda", "scheduler", "mq-deadline"),
+		}
+		mts[0].Description = "average time"
+
+		buf := &bytes.Buffer{}
+		Write(buf, mts, false)
+		So(buf.String(), ShouldEqual, "# HELP intel_iostat_device_await average time\n"+
+			"# TYPE intel_iostat_device_await gauge\n"+
+			"intel_iostat_device_await{device=\"sdb\"} 1.5\n"+
+			"intel_iostat_device_await{device=\"sda\"} 2\n")
+
+		buf.Reset()
+		Write(buf, []plugin.Metric{deviceMetric("sda", "counter/reads_completed", uint64(7))}, false)
+		So(buf.String(), ShouldEqual, "# TYPE intel_iostat_device_counter_reads_completed_total counter\n"+
+			"intel_iostat_device_counter_reads_completed_total{device=\"sda\"} 7\n")
+
+		buf.Reset()
+		Write(buf, mts[1:2], true)
+		So(buf.String(), ShouldEndWith, "# EOF\n")
+
+		counter := deviceMetric("sda", "counter/reads_completed", uint64(7))
+		counter.Description = "reads completed"
+		buf.Reset()
+		Write(buf, []plugin.Metric{counter}, true)
+		So(buf.String(), ShouldEqual, "# HELP intel_iostat_device_counter_reads_completed reads completed\n"+
+			"# TYPE intel_iostat_device_counter_reads_completed counter\n"+
+			"intel_iostat_device_counter_reads_completed_total{device=\"sda\"} 7\n"+
+			"# EOF\n")
+	})
+
+	Convey("Given handler collect metrics on scrape respecting cache TTL", t, func() {
+		collector := &mockCollector{}
+		handler := NewHandler(collector, plugin.Config{}, time.Hour)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		So(rec.Code, ShouldEqual, http.StatusOK)
+		So(rec.Header().Get("Content-Type"), ShouldStartWith, "text/plain")
+		So(rec.Body.String(), ShouldContainSubstring, "intel_iostat_avg_cpu_idle_percent 99.5\n")
+
+		rec = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+		handler.ServeHTTP(rec, req)
+		So(rec.Header().Get("Content-Type"), ShouldStartWith, "application/openmetrics-text")
+		So(collector.collections, ShouldEqual, 1)
+		// metrics not reported on the host are not requested
+		So(collector.requested, ShouldHaveLength, 1)
+		So(collector.requested[0].Namespace.String(), ShouldEqual, "/intel/iostat/avg-cpu/%idle")
+
+		handler.cacheTTL = 0
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		So(collector.collections, ShouldEqual, 2)
+	})
+}
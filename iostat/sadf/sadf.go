@@ -0,0 +1,162 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sadf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Command exports statistics recorded by sadc in sysstat data files
+const Command = "sadf"
+
+// Record holds statistics of an interval recorded in a data file, named as
+// iostat reports them
+type Record struct {
+	// Time is the end of the interval
+	Time time.Time
+	// CPU holds utilization of all CPUs, e.g. %user
+	CPU map[string]float64
+	// Disks holds statistics of each device, e.g. await
+	Disks map[string]map[string]float64
+}
+
+// metric maps a statistic exported by sadf to a metric reported by iostat
+type metric struct {
+	name  string
+	scale float64
+}
+
+// cpuMetrics are statistics reported by sar -u
+var cpuMetrics = map[string]metric{
+	"user":   {"%user", 1},
+	"nice":   {"%nice", 1},
+	"system": {"%system", 1},
+	"iowait": {"%iowait", 1},
+	"steal":  {"%steal", 1},
+	"idle":   {"%idle", 1},
+}
+
+// diskMetrics are statistics reported by sar -d, older sysstat reports
+// throughput in sectors per second, sysstat 12 in kilobytes per second;
+// tps has no counterpart in iostat metrics
+var diskMetrics = map[string]metric{
+	"rd_sec":   {"rkB_per_sec", 0.5},
+	"wr_sec":   {"wkB_per_sec", 0.5},
+	"rkB":      {"rkB_per_sec", 1},
+	"wkB":      {"wkB_per_sec", 1},
+	"dkB":      {"dkB_per_sec", 1},
+	"avgrq-sz": {"avgrq-sz", 1},
+	"areq-sz":  {"areq-sz", 1},
+	"avgqu-sz": {"avgqu-sz", 1},
+	"aqu-sz":   {"aqu-sz", 1},
+	"await":    {"await", 1},
+	"svctm":    {"svctm", 1},
+	"util":     {"%util", 1},
+}
+
+type document struct {
+	Sysstat struct {
+		Hosts []struct {
+			Statistics []statistics `json:"statistics"`
+		} `json:"hosts"`
+	} `json:"sysstat"`
+}
+
+type statistics struct {
+	Timestamp *struct {
+		Date string `json:"date"`
+		Time string `json:"time"`
+		UTC  int    `json:"utc"`
+	} `json:"timestamp"`
+	CPULoad []map[string]interface{} `json:"cpu-load"`
+	Disk    []map[string]interface{} `json:"disk"`
+}
+
+// Args returns arguments of sadf exporting CPU and disk statistics recorded in
+// the data file in JSON, with devices named as in /dev; start and end limit
+// the time of day (hh:mm:ss) of exported records if they are not empty
+func Args(file, start, end string) []string {
+	args := []string{"-j"}
+	if start != "" {
+		args = append(args, "-s", start)
+	}
+	if end != "" {
+		args = append(args, "-e", end)
+	}
+	return append(args, file, "--", "-u", "-d", "-p")
+}
+
+// Parse returns records of sadf output in JSON, in the order of the output
+func Parse(reader io.Reader) ([]Record, error) {
+	var doc document
+	if err := json.NewDecoder(reader).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("Invalid sadf output: %v", err)
+	}
+
+	records := []Record{}
+	for _, host := range doc.Sysstat.Hosts {
+		for _, s := range host.Statistics {
+			if s.Timestamp == nil {
+				continue
+			}
+			// sadf prints time in UTC unless it is told to print local time
+			location := time.Local
+			if s.Timestamp.UTC == 1 {
+				location = time.UTC
+			}
+			t, err := time.ParseInLocation("2006-01-02 15:04:05", s.Timestamp.Date+" "+s.Timestamp.Time, location)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid timestamp of sadf record: %v", err)
+			}
+
+			record := Record{Time: t, CPU: map[string]float64{}, Disks: map[string]map[string]float64{}}
+			for _, cpu := range s.CPULoad {
+				if fmt.Sprint(cpu["cpu"]) == "all" {
+					record.CPU = values(cpu, cpuMetrics)
+				}
+			}
+			for _, disk := range s.Disk {
+				if dev, ok := disk["disk-device"].(string); ok {
+					record.Disks[dev] = values(disk, diskMetrics)
+				}
+			}
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// values returns known statistics renamed and scaled to iostat metrics
+func values(stats map[string]interface{}, metrics map[string]metric) map[string]float64 {
+	result := map[string]float64{}
+	for name, v := range stats {
+		m, ok := metrics[name]
+		if !ok {
+			continue
+		}
+		if f, ok := v.(float64); ok {
+			result[m.name] = f * m.scale
+		}
+	}
+	return result
+}
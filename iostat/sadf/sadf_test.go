@@ -0,0 +1,92 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sadf
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSadf(t *testing.T) {
+	Convey("Given data file build sadf arguments", t, func() {
+		So(Args("/var/log/sa/sa01", "", ""), ShouldResemble, []string{"-j", "/var/log/sa/sa01", "--", "-u", "-d", "-p"})
+		So(Args("sa01", "10:00:00", "11:00:00"), ShouldResemble,
+			[]string{"-j", "-s", "10:00:00", "-e", "11:00:00", "sa01", "--", "-u", "-d", "-p"})
+	})
+
+	Convey("Given sadf output of sysstat 11 parse records", t, func() {
+		file, err := os.Open("../testdata/sadf/sysstat11.json")
+		So(err, ShouldBeNil)
+		defer file.Close()
+
+		records, err := Parse(file)
+		So(err, ShouldBeNil)
+		So(records, ShouldHaveLength, 2)
+		So(records[0].Time, ShouldResemble, time.Date(2017, 3, 1, 10, 10, 1, 0, time.UTC))
+		So(records[1].Time, ShouldResemble, time.Date(2017, 3, 1, 10, 20, 1, 0, time.UTC))
+		So(records[0].CPU, ShouldResemble, map[string]float64{
+			"%user": 1.25, "%nice": 0, "%system": 0.5, "%iowait": 0.1, "%steal": 0, "%idle": 98.15,
+		})
+		So(records[0].Disks, ShouldHaveLength, 2)
+		So(records[0].Disks["sda"], ShouldResemble, map[string]float64{
+			"rkB_per_sec": 64,
+			"wkB_per_sec": 1234.56,
+			"avgrq-sz":    259.71,
+			"avgqu-sz":    0.04,
+			"await":       3.75,
+			"svctm":       0.4,
+			"%util":       0.4,
+		})
+	})
+
+	Convey("Given sadf output of sysstat 12 parse records", t, func() {
+		file, err := os.Open("../testdata/sadf/sysstat12.json")
+		So(err, ShouldBeNil)
+		defer file.Close()
+
+		records, err := Parse(file)
+		So(err, ShouldBeNil)
+		So(records, ShouldHaveLength, 1)
+		// local time is printed if utc is 0
+		So(records[0].Time, ShouldResemble, time.Date(2022, 9, 1, 12, 0, 1, 0, time.Local))
+		So(records[0].CPU["%steal"], ShouldEqual, 0.25)
+		So(records[0].Disks["nvme0n1"], ShouldResemble, map[string]float64{
+			"rkB_per_sec": 0,
+			"wkB_per_sec": 200,
+			"dkB_per_sec": 0,
+			"areq-sz":     4,
+			"aqu-sz":      0.1,
+			"await":       1,
+			"%util":       5.25,
+		})
+	})
+
+	Convey("Given invalid sadf output return an error", t, func() {
+		_, err := Parse(strings.NewReader("Invalid system activity file"))
+		So(err, ShouldNotBeNil)
+
+		_, err = Parse(strings.NewReader(`{"sysstat": {"hosts": [{"statistics": [{"timestamp": {"date": "01/03/17", "time": "10:10:01"}}]}]}}`))
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,78 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iostat
+
+import (
+	"math"
+	"sort"
+)
+
+// sampleStats are statistics published for device metrics when multiple samples are taken
+var sampleStats = []string{"min", "max", "mean", "p50", "p95", "p99"}
+
+// summarize merges reports of all samples into a single one; a metric is
+// represented by the mean of its samples and each device metric gets
+// statistics of the samples published as sibling namespaces (e.g. .../await/p95)
+func summarize(reports []map[string]float64) map[string]float64 {
+	switch len(reports) {
+	case 0:
+		return map[string]float64{}
+	case 1:
+		return reports[0]
+	}
+
+	samples := map[string][]float64{}
+	for _, report := range reports {
+		for k, v := range report {
+			samples[k] = append(samples[k], v)
+		}
+	}
+
+	data := map[string]float64{}
+	for k, values := range samples {
+		sort.Float64s(values)
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		mean := sum / float64(len(values))
+		data[k] = mean
+
+		if _, _, ok := deviceKey(k); !ok {
+			continue
+		}
+		data[k+"/min"] = values[0]
+		data[k+"/max"] = values[len(values)-1]
+		data[k+"/mean"] = mean
+		data[k+"/p50"] = percentile(values, 50)
+		data[k+"/p95"] = percentile(values, 95)
+		data[k+"/p99"] = percentile(values, 99)
+	}
+	return data
+}
+
+// percentile returns p-th percentile of sorted values using the nearest-rank method
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
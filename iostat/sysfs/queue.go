@@ -0,0 +1,100 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sysfs
+
+import (
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+)
+
+const (
+	// QueueMetric is a namespace element grouping in-flight I/O and queue settings of a device
+	QueueMetric = "queue"
+)
+
+// queueSettings are numeric files of /sys/block/[device]/queue published as they are
+var queueSettings = []string{
+	"nr_requests",
+	"read_ahead_kb",
+	"max_sectors_kb",
+	"rotational",
+	"logical_block_size",
+	"physical_block_size",
+}
+
+// QueueMetrics lists names of metrics returned by Queue
+var QueueMetrics = append([]string{"inflight_reads", "inflight_writes", "scheduler", "size_bytes"}, queueSettings...)
+
+// Queue returns in-flight I/O, size and queue settings of the block device;
+// partitions have no queue of their own, so only in-flight I/O and size are
+// returned for them. The scheduler is returned as a string, other values as uint64.
+func Queue(fs *hostfs.FS, dev string) map[string]interface{} {
+	data := map[string]interface{}{}
+
+	if inflight, err := ReadUints(fs.Sys("class", "block", dev, "inflight")); err == nil && len(inflight) == 2 {
+		data["inflight_reads"] = inflight[0]
+		data["inflight_writes"] = inflight[1]
+	} else {
+		logSkipped(dev, "inflight", err)
+	}
+
+	if size, err := ReadUint(fs.Sys("class", "block", dev, "size")); err == nil {
+		data["size_bytes"] = size * sectorSize
+	} else {
+		logSkipped(dev, "size", err)
+	}
+
+	queue := fs.Sys("class", "block", dev, QueueMetric)
+	for _, name := range queueSettings {
+		if v, err := ReadUint(queue + "/" + name); err == nil {
+			data[name] = v
+		} else {
+			logSkipped(dev, name, err)
+		}
+	}
+
+	if scheduler, err := ReadString(queue + "/scheduler"); err == nil {
+		data["scheduler"] = activeScheduler(scheduler)
+	} else {
+		logSkipped(dev, "scheduler", err)
+	}
+	return data
+}
+
+// activeScheduler returns the scheduler marked with brackets in the list of
+// available ones, e.g. "noop [deadline] cfq"
+func activeScheduler(s string) string {
+	for _, f := range strings.Fields(s) {
+		if strings.HasPrefix(f, "[") && strings.HasSuffix(f, "]") {
+			return strings.Trim(f, "[]")
+		}
+	}
+	return s
+}
+
+func logSkipped(dev, name string, err error) {
+	log.WithFields(log.Fields{
+		"device": dev,
+		"metric": name,
+		"error":  err,
+	}).Debug("failed to read device attribute")
+}
@@ -0,0 +1,95 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sysfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+)
+
+const (
+	// sectorSize is a size of sector used by the kernel in block device statistics
+	sectorSize = 512
+)
+
+// ReadString returns trimmed content of the file
+func ReadString(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// ReadUint returns unsigned integer stored in the file
+func ReadUint(path string) (uint64, error) {
+	s, err := ReadString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// ReadUints returns whitespace separated unsigned integers stored in the file
+func ReadUints(path string) ([]uint64, error) {
+	s, err := ReadString(path)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(s)
+	values := make([]uint64, len(fields))
+	for i, f := range fields {
+		values[i], err = strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// IsPartition checks whether the block device is a partition of another device
+func IsPartition(fs *hostfs.FS, dev string) bool {
+	_, err := os.Stat(fs.Sys("class", "block", dev, "partition"))
+	return err == nil
+}
+
+// IsRotational checks whether the block device is a rotational disk; partitions
+// have no queue of their own, so the queue of the parent device is checked
+func IsRotational(fs *hostfs.FS, dev string) (bool, error) {
+	path := fs.Sys("class", "block", dev)
+	if IsPartition(fs, dev) {
+		// /sys/class/block/[partition] links to a directory nested in the one of its parent
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return false, err
+		}
+		path = filepath.Dir(resolved)
+	}
+	v, err := ReadUint(filepath.Join(path, QueueMetric, "rotational"))
+	if err != nil {
+		return false, err
+	}
+	return v == 1, nil
+}
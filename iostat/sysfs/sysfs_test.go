@@ -0,0 +1,112 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sysfs
+
+import (
+	"testing"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var fs = hostfs.New("../testdata/host")
+
+func TestQueue(t *testing.T) {
+	Convey("Given block device read its in-flight I/O and queue settings", t, func() {
+		So(Queue(fs, "sda"), ShouldResemble, map[string]interface{}{
+			"inflight_reads":      uint64(2),
+			"inflight_writes":     uint64(5),
+			"size_bytes":          uint64(500107862016),
+			"nr_requests":         uint64(128),
+			"read_ahead_kb":       uint64(128),
+			"max_sectors_kb":      uint64(1280),
+			"rotational":          uint64(1),
+			"logical_block_size":  uint64(512),
+			"physical_block_size": uint64(4096),
+			"scheduler":           "deadline",
+		})
+	})
+
+	Convey("Given partition read its in-flight I/O and size", t, func() {
+		So(Queue(fs, "sda1"), ShouldResemble, map[string]interface{}{
+			"inflight_reads":  uint64(0),
+			"inflight_writes": uint64(1),
+			"size_bytes":      uint64(1048576),
+		})
+	})
+
+	Convey("Given unknown device return no data", t, func() {
+		So(Queue(fs, "sdz"), ShouldBeEmpty)
+	})
+
+	Convey("Given list of schedulers return the active one", t, func() {
+		So(activeScheduler("[mq-deadline] kyber none"), ShouldEqual, "mq-deadline")
+		So(activeScheduler("none"), ShouldEqual, "none")
+	})
+}
+
+func TestIsRotational(t *testing.T) {
+	Convey("Given block device check whether it is rotational", t, func() {
+		rotational, err := IsRotational(fs, "sda")
+		So(err, ShouldBeNil)
+		So(rotational, ShouldBeTrue)
+
+		rotational, err = IsRotational(fs, "sdb")
+		So(err, ShouldBeNil)
+		So(rotational, ShouldBeFalse)
+	})
+
+	Convey("Given partition check its parent device", t, func() {
+		rotational, err := IsRotational(fs, "sda1")
+		So(err, ShouldBeNil)
+		So(rotational, ShouldBeTrue)
+	})
+
+	Convey("Given unknown device return an error", t, func() {
+		_, err := IsRotational(fs, "sdz")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestZram(t *testing.T) {
+	Convey("Given zram device read its memory and I/O statistics", t, func() {
+		So(IsZram("zram0"), ShouldBeTrue)
+		So(Zram(fs, "zram0"), ShouldResemble, map[string]interface{}{
+			"orig_data_size_bytes":  uint64(8388608),
+			"compr_data_size_bytes": uint64(2097152),
+			"compression_ratio":     4.0,
+			"mem_used_total_bytes":  uint64(2359296),
+			"mem_limit_bytes":       uint64(0),
+			"mem_used_max_bytes":    uint64(2621440),
+			"same_pages":            uint64(128),
+			"pages_compacted":       uint64(16),
+			"huge_pages":            uint64(2),
+			"failed_reads":          uint64(1),
+			"failed_writes":         uint64(2),
+			"invalid_io":            uint64(0),
+			"notify_free":           uint64(300),
+		})
+	})
+
+	Convey("Given other device return no data", t, func() {
+		So(IsZram("sda"), ShouldBeFalse)
+		So(Zram(fs, "sda"), ShouldBeEmpty)
+	})
+}
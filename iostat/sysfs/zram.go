@@ -0,0 +1,92 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sysfs
+
+import (
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/hostfs"
+)
+
+const (
+	// ZramMetric is a namespace element grouping statistics of a zram device
+	ZramMetric = "zram"
+)
+
+// mmStat are names of columns of /sys/block/zram[id]/mm_stat, huge pages are
+// not reported by old kernels
+var mmStat = []string{
+	"orig_data_size_bytes",
+	"compr_data_size_bytes",
+	"mem_used_total_bytes",
+	"mem_limit_bytes",
+	"mem_used_max_bytes",
+	"same_pages",
+	"pages_compacted",
+	"huge_pages",
+}
+
+// ioStat are names of columns of /sys/block/zram[id]/io_stat
+var ioStat = []string{
+	"failed_reads",
+	"failed_writes",
+	"invalid_io",
+	"notify_free",
+}
+
+// ZramMetrics lists names of metrics returned by Zram
+var ZramMetrics = append(append([]string{"compression_ratio"}, mmStat...), ioStat...)
+
+// IsZram checks whether the block device is a zram device
+func IsZram(dev string) bool {
+	return strings.HasPrefix(dev, "zram")
+}
+
+// Zram returns memory and I/O statistics of the zram device and ratio of
+// original to compressed data size; the ratio is returned as float64, other
+// values as uint64
+func Zram(fs *hostfs.FS, dev string) map[string]interface{} {
+	data := map[string]interface{}{}
+	dir := fs.Sys("class", "block", dev)
+
+	if values, err := ReadUints(dir + "/mm_stat"); err == nil {
+		for i, v := range values {
+			if i < len(mmStat) {
+				data[mmStat[i]] = v
+			}
+		}
+		if len(values) > 1 && values[1] > 0 {
+			data["compression_ratio"] = float64(values[0]) / float64(values[1])
+		}
+	} else {
+		logSkipped(dev, "mm_stat", err)
+	}
+
+	if values, err := ReadUints(dir + "/io_stat"); err == nil {
+		for i, v := range values {
+			if i < len(ioStat) {
+				data[ioStat[i]] = v
+			}
+		}
+	} else {
+		logSkipped(dev, "io_stat", err)
+	}
+	return data
+}
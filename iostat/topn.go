@@ -0,0 +1,126 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iostat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/parser"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+// rankTag is a tag of device metrics holding rank of the device
+const rankTag = "rank"
+
+// rankings are metrics devices can be ranked by, throughput is named by the
+// unit selected in config
+var rankings = map[string]func(values map[string]float64, unit string) float64{
+	"%util": func(values map[string]float64, unit string) float64 { return values["%util"] },
+	"await": func(values map[string]float64, unit string) float64 { return deviceAwait(values) },
+	"tps": func(values map[string]float64, unit string) float64 {
+		return values["r_per_sec"] + values["w_per_sec"]
+	},
+	"kB/s": func(values map[string]float64, unit string) float64 {
+		return values[unitName("rkB_per_sec", unit)] + values[unitName("wkB_per_sec", unit)]
+	},
+}
+
+// topDevices keeps in data metrics of N devices reported by iostat ranked
+// highest by the metric selected in config, metrics of other devices (also of
+// those known from diskstats only) are removed; metrics of the ALL group are
+// always kept. Returned are ranks of the kept devices, starting at 1.
+func topDevices(cfg plugin.Config, data map[string]interface{}, reported []string) (map[string]int, error) {
+	n := getInt(cfg, cfgTopDevices, 0)
+	if n <= 0 {
+		return nil, nil
+	}
+	naming, err := getNaming(cfg)
+	if err != nil {
+		return nil, err
+	}
+	value, err := getRanking(cfg)
+	if err != nil {
+		return nil, err
+	}
+	unit, err := getUnit(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := groupDevices(data)
+	devs := []string{}
+	values := map[string]float64{}
+	for _, dev := range reported {
+		// reported devices may have been hidden already
+		if keys, ok := groups[dev]; ok {
+			devs = append(devs, dev)
+			values[dev] = value(deviceValues(data, keys, naming), unit)
+		}
+	}
+	// devices are sorted by name, so ties are ranked by name
+	sort.Strings(devs)
+	sort.Stable(byValue{devs: devs, values: values})
+
+	ranks := map[string]int{}
+	for i, dev := range devs {
+		if int64(i) < n {
+			ranks[dev] = i + 1
+		}
+	}
+	for dev, keys := range groups {
+		if _, ok := ranks[dev]; !ok {
+			removeKeys(data, keys)
+		}
+	}
+	return ranks, nil
+}
+
+// getRanking returns the metric devices are ranked by selected in config
+func getRanking(cfg plugin.Config) (func(values map[string]float64, unit string) float64, error) {
+	naming, err := getNaming(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// devices may be ranked by the name of the metric in the naming scheme,
+	// metrics they can be ranked by have a single legacy name
+	by := parser.Legacy(getString(cfg, cfgTopDevicesBy, defaultTopDevicesBy), naming)[0]
+	value, ok := rankings[by]
+	if !ok {
+		names := make([]string, 0, len(rankings))
+		for name := range rankings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("Invalid %s %q (supported: %s)", cfgTopDevicesBy, by, strings.Join(names, ", "))
+	}
+	return value, nil
+}
+
+// byValue sorts devices by their values in descending order
+type byValue struct {
+	devs   []string
+	values map[string]float64
+}
+
+func (b byValue) Len() int           { return len(b.devs) }
+func (b byValue) Swap(i, j int)      { b.devs[i], b.devs[j] = b.devs[j], b.devs[i] }
+func (b byValue) Less(i, j int) bool { return b.values[b.devs[i]] > b.values[b.devs[j]] }
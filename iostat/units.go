@@ -0,0 +1,149 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iostat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/mdraid"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/parser"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+// units of throughput, iostat always reports throughput in kilobytes
+const (
+	unitBytes = "bytes"
+	unitKB    = "kB"
+	unitMB    = "MB"
+
+	// sectorBytes is a size of sector in which iostat reports avgrq-sz
+	sectorBytes = 512
+)
+
+// unitScales are factors converting kilobytes into units
+var unitScales = map[string]float64{
+	unitBytes: 1024,
+	unitKB:    1,
+	unitMB:    1.0 / 1024,
+}
+
+// throughputMetrics are device metrics reported in kilobytes per second,
+// "kB" in their names is replaced with the selected unit
+var throughputMetrics = map[string]bool{
+	"rkB_per_sec": true,
+	"wkB_per_sec": true,
+	"dkB_per_sec": true,
+	// synchronization speed of md arrays, see mdraid.Metrics
+	"sync_speed_kB_per_sec": true,
+}
+
+// requestSize is a canonical byte-based metric of request size
+type requestSize struct {
+	name  string
+	scale float64
+}
+
+// requestSizes maps request size metrics reported by iostat to canonical ones;
+// older sysstat reports avgrq-sz in sectors, sysstat 12 reports sizes in kilobytes
+var requestSizes = map[string]requestSize{
+	"avgrq-sz": {"avgrq_bytes", sectorBytes},
+	"areq-sz":  {"avgrq_bytes", 1024},
+	"rareq-sz": {"rareq_bytes", 1024},
+	"wareq-sz": {"wareq_bytes", 1024},
+	"dareq-sz": {"dareq_bytes", 1024},
+}
+
+// getUnit returns throughput unit selected in config
+func getUnit(cfg plugin.Config) (string, error) {
+	unit := getString(cfg, cfgUnits, unitKB)
+	if _, ok := unitScales[unit]; !ok {
+		return "", fmt.Errorf("Invalid %s %q (supported: %s, %s, %s)", cfgUnits, unit, unitBytes, unitKB, unitMB)
+	}
+	return unit, nil
+}
+
+// unitName returns name of the device metric in the unit
+func unitName(name, unit string) string {
+	if throughputMetrics[name] {
+		return strings.Replace(name, unitKB, unit, 1)
+	}
+	return name
+}
+
+// convertUnits converts throughput of devices into the unit, renaming its
+// metrics wherever they appear in namespaces (e.g. also .../anomaly/rkB_per_sec),
+// and adds canonical request size metrics in bytes; metrics are named in the
+// naming scheme
+func convertUnits(namespaces []string, data map[string]interface{}, unit, naming string) ([]string, map[string]interface{}) {
+	converted := make([]string, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		converted = append(converted, convertNamespace(namespace, unit, naming))
+		if elems := strings.Split(namespace, "/"); len(elems) > 5 && elems[3] == deviceMetric {
+			if size, ok := requestSizes[legacyName(elems[5], naming)]; ok {
+				elems[5] = parser.Rename(size.name, naming)
+				converted = append(converted, strings.Join(elems, "/"))
+			}
+		}
+	}
+
+	result := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		elems := strings.Split(k, "/")
+		f, isFloat := v.(float64)
+		// elems[0] is empty, the device metric is the 5th element of the namespace
+		if isFloat && len(elems) > 5 && elems[3] == deviceMetric {
+			name := legacyName(elems[5], naming)
+			if size, ok := requestSizes[name]; ok {
+				elems[5] = parser.Rename(size.name, naming)
+				result[strings.Join(elems, "/")] = f * size.scale
+			}
+			if throughputMetrics[name] {
+				v = f * unitScales[unit]
+			}
+		}
+		// e.g. /intel/iostat/device/md0/md/sync_speed_kB_per_sec
+		if speed, ok := v.(uint64); ok && len(elems) == 7 && elems[3] == deviceMetric && elems[5] == mdraid.MdMetric && throughputMetrics[legacyName(elems[6], naming)] {
+			v = scaleUint(speed, unit)
+		}
+		result[convertNamespace(k, unit, naming)] = v
+	}
+	return converted, result
+}
+
+// scaleUint converts kilobytes into the unit, the value stays integer unless
+// the unit is larger than a kilobyte
+func scaleUint(v uint64, unit string) interface{} {
+	if scale := unitScales[unit]; scale < 1 {
+		return float64(v) * scale
+	}
+	return v * uint64(unitScales[unit])
+}
+
+// convertNamespace renames throughput metrics, named in the naming scheme, in the namespace
+func convertNamespace(namespace, unit, naming string) string {
+	elems := strings.Split(namespace, "/")
+	for i, e := range elems {
+		if name := legacyName(e, naming); throughputMetrics[name] {
+			elems[i] = parser.Rename(unitName(name, unit), naming)
+		}
+	}
+	return strings.Join(elems, "/")
+}
@@ -20,25 +20,93 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/parser"
 	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
 )
 
 const (
-	pluginName    = "iostat"
 	pluginVersion = 7
+
+	cacheTTL = 1 * time.Second
+
+	// environment variables overriding vendor and type elements of namespaces
+	envVendor = "SNAP_IOSTAT_VENDOR"
+	envType   = "SNAP_IOSTAT_TYPE"
 )
 
+// standalone modes selected by the first command line argument
+var modes = map[string]func(args []string, prefix parser.Prefix) int{
+	"backfill": backfill,
+	"collect":  collect,
+	"serve":    serve,
+}
+
 // plugin bootstrap
 func main() {
+	prefix, err := parser.NewPrefix(os.Getenv(envVendor), os.Getenv(envType))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if len(os.Args) > 1 {
+		if mode, ok := modes[os.Args[1]]; ok {
+			os.Exit(mode(os.Args[2:], prefix))
+		}
+	}
 
 	plugin.StartCollector(
-		iostat.NewIostatCollector(),
-		pluginName,
+		iostat.NewIostatCollector(prefix),
+		// forks publishing metrics under their own type are loaded as separate plugins
+		prefix.Type,
 		pluginVersion,
 		plugin.Exclusive(true),
-		plugin.CacheTTL(1*time.Second),
+		plugin.CacheTTL(cacheTTL),
 	)
 }
+
+// parseConfig decodes plugin configuration given in JSON format, whole numbers
+// are decoded as int64 as they are delivered by Snap
+func parseConfig(s string) (plugin.Config, error) {
+	config := plugin.Config{}
+	if strings.TrimSpace(s) == "" {
+		return config, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewBufferString(s))
+	decoder.UseNumber()
+	raw := map[string]interface{}{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	for k, v := range raw {
+		if n, ok := v.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				config[k] = i
+			} else if f, err := n.Float64(); err == nil {
+				config[k] = f
+			}
+			continue
+		}
+		config[k] = v
+	}
+	return config, nil
+}
+
+// addPrefixFlags adds flags setting vendor and type elements of namespaces,
+// their defaults are the prefix taken from the environment
+func addPrefixFlags(flags *flag.FlagSet, prefix parser.Prefix) (vendor, nsType *string) {
+	vendor = flags.String("vendor", prefix.Vendor, "vendor element of namespaces (also set by "+envVendor+")")
+	nsType = flags.String("type", prefix.Type, "type element of namespaces (also set by "+envType+")")
+	return vendor, nsType
+}
@@ -20,11 +20,15 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"testing"
 
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/parser"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -38,3 +42,72 @@ func TestMain(t *testing.T) {
 		fmt.Println("Do not find iostat executable. Test skipped")
 	}
 }
+
+func TestParseConfig(t *testing.T) {
+	Convey("Given JSON config decode it as Snap does", t, func() {
+		cfg, err := parseConfig(`{"ReportSinceBoot": true, "Count": 3, "Ratio": 0.5, "Name": "x"}`)
+		So(err, ShouldBeNil)
+		So(cfg["ReportSinceBoot"], ShouldEqual, true)
+		So(cfg["Count"], ShouldEqual, int64(3))
+		So(cfg["Ratio"], ShouldEqual, 0.5)
+		So(cfg["Name"], ShouldEqual, "x")
+
+		cfg, err = parseConfig("")
+		So(err, ShouldBeNil)
+		So(cfg, ShouldBeEmpty)
+
+		_, err = parseConfig("{")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestFilterMetrics(t *testing.T) {
+	mts := []plugin.Metric{
+		plugin.Metric{Namespace: plugin.NewNamespace("intel", "iostat", "avg-cpu", "%idle")},
+		plugin.Metric{Namespace: plugin.NewNamespace("intel", "iostat", "avg-cpu", "%user")},
+		plugin.Metric{Namespace: plugin.NewNamespace("intel", "iostat", "device").
+			AddDynamicElement("device_id", "Device ID").
+			AddStaticElement("await")},
+		plugin.Metric{Namespace: plugin.NewNamespace("intel", "iostat", "device").
+			AddDynamicElement("device_id", "Device ID").
+			AddStaticElement("%util")},
+	}
+
+	Convey("Given filters select matching metric types", t, func() {
+		So(len(filterMetrics(mts, nil)), ShouldEqual, 4)
+		So(len(filterMetrics(mts, filters{"/intel/iostat/avg-cpu/*"})), ShouldEqual, 2)
+
+		filtered := filterMetrics(mts, filters{"/intel/iostat/device/*/await"})
+		So(len(filtered), ShouldEqual, 1)
+		So(filtered[0].Namespace.String(), ShouldEqual, "/intel/iostat/device/*/await")
+
+		filtered = filterMetrics(mts, filters{"/intel/iostat/device/sda/%util"})
+		So(len(filtered), ShouldEqual, 1)
+		So(filtered[0].Namespace.String(), ShouldEqual, "/intel/iostat/device/sda/%util")
+		So(filtered[0].Namespace[3].IsDynamic(), ShouldBeTrue)
+		So(mts[3].Namespace[3].Value, ShouldEqual, "*")
+
+		So(filterMetrics(mts, filters{"/intel/iostat/device/sda/bad"}), ShouldBeEmpty)
+	})
+}
+
+func TestCollect(t *testing.T) {
+	Convey("Given no filters dump all collected metrics as a JSON document", t, func() {
+		out, err := ioutil.TempFile("", "collect")
+		So(err, ShouldBeNil)
+		defer os.Remove(out.Name())
+		defer out.Close()
+
+		stdout := os.Stdout
+		os.Stdout = out
+		code := collect([]string{"--config", `{"Replay": "iostat/testdata/replay", "HostRoot": "iostat/testdata/host"}`}, parser.DefaultPrefix)
+		os.Stdout = stdout
+		So(code, ShouldEqual, 0)
+
+		dump, err := ioutil.ReadFile(out.Name())
+		So(err, ShouldBeNil)
+		metrics := []map[string]interface{}{}
+		So(json.Unmarshal(dump, &metrics), ShouldBeNil)
+		So(metrics, ShouldNotBeEmpty)
+	})
+}
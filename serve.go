@@ -0,0 +1,69 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/parser"
+	"github.com/intelsdi-x/snap-plugin-collector-iostat/iostat/prometheus"
+)
+
+// serve runs plugin as a standalone Prometheus exporter
+func serve(args []string, prefix parser.Prefix) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	listen := flags.String("listen", "127.0.0.1:9163", "address to serve metrics on")
+	path := flags.String("path", "/metrics", "HTTP path to serve metrics on")
+	config := flags.String("config", "", "plugin configuration in JSON format, e.g. {\"ReportSinceBoot\": true}")
+	ttl := flags.Duration("cache-ttl", cacheTTL, "time for which collected metrics are reused between scrapes")
+	vendor, nsType := addPrefixFlags(flags, prefix)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	prefix, err := parser.NewPrefix(*vendor, *nsType)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	cfg, err := parseConfig(*config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid config:", err)
+		return 2
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(*path, prometheus.NewHandler(iostat.NewIostatCollector(prefix), cfg, *ttl))
+
+	log.WithFields(log.Fields{
+		"address": *listen,
+		"path":    *path,
+	}).Info("serving iostat metrics")
+	if err := http.ListenAndServe(*listen, mux); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}